@@ -0,0 +1,66 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+/*
+Oggcheck validates ogg files, printing every violation of the ogg format it finds.
+
+Usage:
+
+	oggcheck [-strict] [file ...]
+
+With no files, it reads the standard input.
+Each finding is printed as the file name, the byte offset,
+the serial and sequence numbers of the page, the severity, the rule, and a description.
+
+The exit status is 1 if there are any errors, or with -strict, any warnings,
+and 2 if a file can't be read.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"mccoy.space/g/ogg"
+)
+
+var strict = flag.Bool("strict", false, "fail on warnings as well as errors")
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: oggcheck [-strict] [file ...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	status := 0
+	check := func(name string, r io.Reader) {
+		findings, err := ogg.Validate(r)
+		for _, f := range findings {
+			fmt.Printf("%s: %v\n", name, f)
+			if (f.Severity == ogg.Error || *strict) && status == 0 {
+				status = 1
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "oggcheck: %s: %v\n", name, err)
+			status = 2
+		}
+	}
+
+	if flag.NArg() == 0 {
+		check("<stdin>", os.Stdin)
+	}
+	for _, name := range flag.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "oggcheck:", err)
+			status = 2
+			continue
+		}
+		check(name, f)
+		f.Close()
+	}
+	os.Exit(status)
+}
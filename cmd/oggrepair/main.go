@@ -0,0 +1,112 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+/*
+Oggrepair rebuilds a damaged ogg file, as described for ogg.Repair.
+
+Usage:
+
+	oggrepair [-v] input output
+
+Either file may be "-" for the standard input or output,
+and the output may be the input, which is replaced once it's repaired.
+With -v, the damage that was skipped and a summary of the changes are printed
+to the standard error.
+*/
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"mccoy.space/g/ogg"
+)
+
+var verbose = flag.Bool("v", false, "describe the repairs")
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: oggrepair [-v] input output")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	err := repair(flag.Arg(0), flag.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "oggrepair:", err)
+		os.Exit(1)
+	}
+}
+
+func repair(in, out string) error {
+	var src io.Reader = os.Stdin
+	if in != "-" {
+		f, err := os.Open(in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		src = f
+	}
+
+	// A file is written under a temporary name and renamed when it's complete,
+	// so the input may also be the output, and errors leave no partial file.
+	dst := os.Stdout
+	if out != "-" {
+		f, err := os.CreateTemp(filepath.Dir(out), "."+filepath.Base(out)+".*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+		dst = f
+	}
+
+	w := bufio.NewWriter(dst)
+	rep, err := ogg.Repair(w, bufio.NewReader(src))
+	if err != nil {
+		return err
+	}
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	if out != "-" {
+		err = dst.Sync()
+		if cerr := dst.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+		mode := os.FileMode(0644)
+		if fi, err := os.Stat(out); err == nil {
+			mode = fi.Mode().Perm()
+		}
+		_ = os.Chmod(dst.Name(), mode)
+		err = os.Rename(dst.Name(), out)
+		if err != nil {
+			return err
+		}
+	}
+
+	if *verbose {
+		for _, d := range rep.Damage {
+			if d.Err == nil {
+				fmt.Fprintf(os.Stderr, "%d: skipped %d bytes outside of pages\n", d.Offset, d.Size)
+			} else {
+				fmt.Fprintf(os.Stderr, "%d: skipped %d bytes of page %d of stream %d: %v\n", d.Offset, d.Size, d.Sequence, d.Serial, d.Err)
+			}
+		}
+		fmt.Fprintf(os.Stderr, "%d damaged regions, %d pages dropped, %d packets trimmed, %d pages renumbered, %d streams ended\n",
+			len(rep.Damage), rep.Dropped, rep.Trimmed, rep.Renumbered, len(rep.Ended))
+	}
+	return nil
+}
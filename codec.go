@@ -0,0 +1,175 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package ogg
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Codec describes the encoding encapsulated in a logical stream.
+type Codec struct {
+	// Name is the codec's short name, like "vorbis".
+	Name string
+	// MIMEType is the codec's MIME type, like "audio/vorbis", if it has one.
+	MIMEType string
+	// Headers is the number of header packets at the beginning of the stream,
+	// including the first, or 0 if it isn't known.
+	Headers int
+	// Time maps the stream's granule positions to time,
+	// or is nil if they don't correspond to time.
+	Time func(granule int64) time.Duration
+}
+
+// ErrUnknownCodec is the error used when no registered codec matches a stream's first packet.
+var ErrUnknownCodec = errors.New("unknown codec")
+
+// ErrShortHeader is the error used when a codec's header packet is too short.
+var ErrShortHeader = errors.New("codec header is too short")
+
+type codecFormat struct {
+	magic    string
+	identify func(bos []byte) (Codec, error)
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   []codecFormat
+)
+
+// RegisterCodec registers a codec for logical streams whose first packet begins with magic.
+// The identify function describes the stream, given its first packet.
+// Codecs registered later take precedence over earlier ones,
+// so applications may replace the codecs built into this package.
+func RegisterCodec(magic string, identify func(bos []byte) (Codec, error)) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs = append(codecs, codecFormat{magic, identify})
+}
+
+// IdentifyCodec identifies the codec of a logical stream from its first packet.
+// Vorbis, Opus, FLAC, Theora, Speex, Kate, and Skeleton are built in.
+func IdentifyCodec(bos []byte) (Codec, error) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	for i := len(codecs) - 1; i >= 0; i-- {
+		if strings.HasPrefix(string(bos), codecs[i].magic) {
+			return codecs[i].identify(bos)
+		}
+	}
+	return Codec{}, ErrUnknownCodec
+}
+
+func init() {
+	RegisterCodec("\x01vorbis", identifyVorbis)
+	RegisterCodec("OpusHead", identifyOpus)
+	RegisterCodec("\x7fFLAC", identifyFLAC)
+	RegisterCodec("\x80theora", identifyTheora)
+	RegisterCodec("Speex   ", identifySpeex)
+	RegisterCodec("\x80kate\x00\x00\x00", identifyKate)
+	RegisterCodec("fishead\x00", identifySkeleton)
+}
+
+// GranuleDuration converts n granules to a duration, at num/den granules per second.
+// Codecs whose granule positions count samples use the sample rate as num and 1 as den.
+func GranuleDuration(n, num, den int64) time.Duration {
+	n *= den
+	return time.Duration(n/num)*time.Second + time.Duration(n%num*int64(time.Second)/num)
+}
+
+// sampleClock returns a Codec.Time for granule positions that count samples at the given rate,
+// less some number of samples skipped at the beginning.
+func sampleClock(rate, skip int64) func(int64) time.Duration {
+	if rate <= 0 {
+		return nil
+	}
+	return func(g int64) time.Duration {
+		return GranuleDuration(g-skip, rate, 1)
+	}
+}
+
+// shiftClock returns a Codec.Time for granule positions split into two parts by shift,
+// as in Theora and Kate, with the given number of granules per second.
+// The offset is added to the sum of the two parts.
+func shiftClock(shift uint, num, den, offset int64) func(int64) time.Duration {
+	if num <= 0 || den <= 0 || shift > 62 {
+		return nil
+	}
+	return func(g int64) time.Duration {
+		return GranuleDuration(g>>shift+g&(1<<shift-1)+offset, num, den)
+	}
+}
+
+func identifyVorbis(bos []byte) (Codec, error) {
+	if len(bos) < 30 {
+		return Codec{}, ErrShortHeader
+	}
+	rate := binary.LittleEndian.Uint32(bos[12:16])
+	return Codec{"vorbis", "audio/vorbis", 3, sampleClock(int64(rate), 0)}, nil
+}
+
+func identifyOpus(bos []byte) (Codec, error) {
+	if len(bos) < 19 {
+		return Codec{}, ErrShortHeader
+	}
+	preskip := binary.LittleEndian.Uint16(bos[10:12])
+	return Codec{"opus", "audio/opus", 2, sampleClock(48000, int64(preskip))}, nil
+}
+
+func identifyFLAC(bos []byte) (Codec, error) {
+	// mapping header, then "fLaC", then the STREAMINFO metadata block
+	if len(bos) < 13+4+34 {
+		return Codec{}, ErrShortHeader
+	}
+	headers := int(binary.BigEndian.Uint16(bos[7:9]))
+	if headers != 0 {
+		headers++
+	}
+	si := bos[17:]
+	rate := int64(si[10])<<12 | int64(si[11])<<4 | int64(si[12])>>4
+	return Codec{"flac", "audio/flac", headers, sampleClock(rate, 0)}, nil
+}
+
+func identifyTheora(bos []byte) (Codec, error) {
+	if len(bos) < 42 {
+		return Codec{}, ErrShortHeader
+	}
+	num := int64(binary.BigEndian.Uint32(bos[22:26]))
+	den := int64(binary.BigEndian.Uint32(bos[26:30]))
+	shift := uint(bos[40]&0x03)<<3 | uint(bos[41]>>5)
+
+	// Granule positions count frames starting from 1 since version 3.2.1,
+	// and the time is that at the end of the frame.
+	offset := int64(1)
+	if bos[7] > 3 || bos[7] == 3 && (bos[8] > 2 || bos[8] == 2 && bos[9] >= 1) {
+		offset = 0
+	}
+	return Codec{"theora", "video/theora", 3, shiftClock(shift, num, den, offset)}, nil
+}
+
+func identifySpeex(bos []byte) (Codec, error) {
+	if len(bos) < 80 {
+		return Codec{}, ErrShortHeader
+	}
+	rate := int32(binary.LittleEndian.Uint32(bos[36:40]))
+	extra := int32(binary.LittleEndian.Uint32(bos[68:72]))
+	return Codec{"speex", "audio/speex", 2 + int(extra), sampleClock(int64(rate), 0)}, nil
+}
+
+func identifyKate(bos []byte) (Codec, error) {
+	if len(bos) < 32 {
+		return Codec{}, ErrShortHeader
+	}
+	shift := uint(bos[15])
+	num := int64(binary.LittleEndian.Uint32(bos[24:28]))
+	den := int64(binary.LittleEndian.Uint32(bos[28:32]))
+	return Codec{"kate", "application/kate", int(bos[11]), shiftClock(shift, num, den, 0)}, nil
+}
+
+func identifySkeleton(bos []byte) (Codec, error) {
+	// Skeleton is entirely header packets, one per stream, and has no timing.
+	return Codec{Name: "skeleton"}, nil
+}
@@ -0,0 +1,114 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package ogg
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestIdentifyCodec(t *testing.T) {
+	vorbis := make([]byte, 30)
+	copy(vorbis, "\x01vorbis")
+	binary.LittleEndian.PutUint32(vorbis[12:], 44100)
+
+	opus := make([]byte, 19)
+	copy(opus, "OpusHead")
+	binary.LittleEndian.PutUint16(opus[10:], 312)
+
+	flac := make([]byte, 51)
+	copy(flac, "\x7fFLAC\x01\x00\x00\x02fLaC")
+	flac[17+10] = 0x0a // 44100 Hz, 20 bits starting here
+	flac[17+11] = 0xc4
+	flac[17+12] = 0x40
+
+	theora := make([]byte, 42)
+	copy(theora, "\x80theora\x03\x02\x01")
+	binary.BigEndian.PutUint32(theora[22:], 30)
+	binary.BigEndian.PutUint32(theora[26:], 1)
+	theora[41] = 6 << 5 // KFGSHIFT
+
+	speex := make([]byte, 80)
+	copy(speex, "Speex   ")
+	binary.LittleEndian.PutUint32(speex[36:], 16000)
+	binary.LittleEndian.PutUint32(speex[68:], 1)
+
+	kate := make([]byte, 64)
+	copy(kate, "\x80kate\x00\x00\x00\x00\x00\x06\x09")
+	kate[15] = 32 // granule shift
+	binary.LittleEndian.PutUint32(kate[24:], 1000)
+	binary.LittleEndian.PutUint32(kate[28:], 1)
+
+	tests := []struct {
+		bos     []byte
+		name    string
+		headers int
+		granule int64
+		time    time.Duration
+	}{
+		{vorbis, "vorbis", 3, 44100 * 3 / 2, 1500 * time.Millisecond},
+		{opus, "opus", 2, 312 + 48000, time.Second},
+		{flac, "flac", 3, 44100 * 2, 2 * time.Second},
+		{theora, "theora", 3, 60<<6 | 30, 3 * time.Second},
+		{speex, "speex", 3, 8000, 500 * time.Millisecond},
+		{kate, "kate", 9, 1000<<32 | 500, 1500 * time.Millisecond},
+		{[]byte("fishead\x00"), "skeleton", 0, 0, 0},
+	}
+
+	for _, x := range tests {
+		c, err := IdentifyCodec(x.bos)
+		if err != nil {
+			t.Fatalf("unexpected IdentifyCodec error for %s: %v", x.name, err)
+		}
+		if c.Name != x.name || c.Headers != x.headers {
+			t.Fatalf("expected %s with %d headers, got %s with %d", x.name, x.headers, c.Name, c.Headers)
+		}
+		if c.Time == nil {
+			if x.time != 0 {
+				t.Fatalf("expected %s to have a Time function", x.name)
+			}
+			continue
+		}
+		if tm := c.Time(x.granule); tm != x.time {
+			t.Fatalf("%s granule %d is %v, expected %v", x.name, x.granule, tm, x.time)
+		}
+	}
+
+	_, err := IdentifyCodec([]byte("\x01vorbis"))
+	if err != ErrShortHeader {
+		t.Fatal("expected ErrShortHeader, got:", err)
+	}
+	_, err = IdentifyCodec([]byte("BBCD\x00"))
+	if err != ErrUnknownCodec {
+		t.Fatal("expected ErrUnknownCodec, got:", err)
+	}
+
+	RegisterCodec("BBCD", func(bos []byte) (Codec, error) {
+		return Codec{Name: "dirac", MIMEType: "video/x-dirac"}, nil
+	})
+	c, err := IdentifyCodec([]byte("BBCD\x00"))
+	if err != nil {
+		t.Fatal("unexpected IdentifyCodec error:", err)
+	}
+	if c.Name != "dirac" {
+		t.Fatal("expected dirac, got", c.Name)
+	}
+}
+
+func TestGranuleDuration(t *testing.T) {
+	tests := []struct {
+		n, num, den int64
+		d           time.Duration
+	}{
+		{48000, 48000, 1, time.Second},
+		{-960, 48000, 1, -20 * time.Millisecond},
+		{30, 30000, 1001, 1001 * time.Millisecond},
+		{44100 * 3600 * 24, 44100, 1, 24 * time.Hour},
+	}
+	for _, x := range tests {
+		if d := GranuleDuration(x.n, x.num, x.den); d != x.d {
+			t.Fatalf("expected %v for %d at %d/%d, got %v", x.d, x.n, x.num, x.den, d)
+		}
+	}
+}
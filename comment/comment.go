@@ -0,0 +1,131 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+/*
+Package comment implements the Vorbis comment structure that carries metadata
+in the comment header packets of Vorbis, Opus, Theora, Speex, and other codecs, as defined in
+https://xiph.org/vorbis/doc/v-comment.html .
+
+Codec-specific framing, like a magic signature, is left to the codec's package.
+*/
+package comment
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+// ErrShort is the error used when a comment structure is truncated.
+var ErrShort = errors.New("truncated comment header")
+
+// A Header is a vendor string and a list of user comments.
+type Header struct {
+	// Vendor identifies the encoder.
+	Vendor string
+	// Comments are the user comments, each of the form "FIELD=value".
+	// Field names are case-insensitive ASCII, and values are UTF-8.
+	Comments []string
+}
+
+// Parse parses a comment structure from the beginning of b.
+// It returns the Header and the number of bytes it occupied.
+func Parse(b []byte) (Header, int, error) {
+	var h Header
+	n := 0
+
+	next := func() (string, bool) {
+		if len(b)-n < 4 {
+			return "", false
+		}
+		l := binary.LittleEndian.Uint32(b[n:])
+		n += 4
+		if uint32(len(b)-n) < l {
+			return "", false
+		}
+		s := string(b[n : n+int(l)])
+		n += int(l)
+		return s, true
+	}
+
+	vendor, ok := next()
+	if !ok {
+		return Header{}, 0, ErrShort
+	}
+	h.Vendor = vendor
+
+	if len(b)-n < 4 {
+		return Header{}, 0, ErrShort
+	}
+	count := binary.LittleEndian.Uint32(b[n:])
+	n += 4
+	// Each comment takes at least 4 bytes, which bounds the allocation.
+	if uint32(len(b)-n)/4 < count {
+		return Header{}, 0, ErrShort
+	}
+
+	if count > 0 {
+		h.Comments = make([]string, 0, count)
+	}
+	for i := uint32(0); i < count; i++ {
+		c, ok := next()
+		if !ok {
+			return Header{}, 0, ErrShort
+		}
+		h.Comments = append(h.Comments, c)
+	}
+	return h, n, nil
+}
+
+// Append appends the encoded comment structure to b and returns the result.
+func (h *Header) Append(b []byte) []byte {
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(h.Vendor)))
+	b = append(b, h.Vendor...)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(h.Comments)))
+	for _, c := range h.Comments {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(c)))
+		b = append(b, c...)
+	}
+	return b
+}
+
+// Get returns the values of the comments with the given field name, in order.
+func (h *Header) Get(field string) []string {
+	var vals []string
+	for _, c := range h.Comments {
+		if v, ok := value(c, field); ok {
+			vals = append(vals, v)
+		}
+	}
+	return vals
+}
+
+// Add adds a comment with the given field name and value.
+func (h *Header) Add(field, val string) {
+	h.Comments = append(h.Comments, field+"="+val)
+}
+
+// Set replaces the comments with the given field name with ones for the given values.
+// With no values, it deletes the field.
+func (h *Header) Set(field string, vals ...string) {
+	kept := h.Comments[:0]
+	for _, c := range h.Comments {
+		if _, ok := value(c, field); !ok {
+			kept = append(kept, c)
+		}
+	}
+	h.Comments = kept
+	for _, v := range vals {
+		h.Add(field, v)
+	}
+}
+
+// value returns the value of comment c if its field name is field.
+func value(c, field string) (string, bool) {
+	if len(c) <= len(field) || c[len(field)] != '=' {
+		return "", false
+	}
+	if !strings.EqualFold(c[:len(field)], field) {
+		return "", false
+	}
+	return c[len(field)+1:], true
+}
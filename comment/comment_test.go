@@ -0,0 +1,49 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package comment
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAppend(t *testing.T) {
+	h := Header{Vendor: "vendor", Comments: []string{"TITLE=a", "Artist=b", "artist=c"}}
+	b := h.Append([]byte("magic"))
+
+	got, n, err := Parse(append(b[5:], 1, 2, 3))
+	if err != nil {
+		t.Fatal("unexpected Parse error:", err)
+	}
+	if n != len(b)-5 {
+		t.Fatalf("expected %d bytes, got %d", len(b)-5, n)
+	}
+	if !reflect.DeepEqual(got, h) {
+		t.Fatalf("expected %+v, got %+v", h, got)
+	}
+
+	for i := 0; i < len(b)-5; i++ {
+		_, _, err = Parse(b[5 : 5+i])
+		if err != ErrShort {
+			t.Fatalf("expected ErrShort for %d bytes, got %v", i, err)
+		}
+	}
+}
+
+func TestFields(t *testing.T) {
+	h := Header{Comments: []string{"TITLE=a", "Artist=b", "artist=c", "ARTISTS=d"}}
+	if v := h.Get("ARTIST"); !reflect.DeepEqual(v, []string{"b", "c"}) {
+		t.Fatal("wrong artists:", v)
+	}
+
+	h.Set("artist", "e")
+	expect := []string{"TITLE=a", "ARTISTS=d", "artist=e"}
+	if !reflect.DeepEqual(h.Comments, expect) {
+		t.Fatalf("expected %q, got %q", expect, h.Comments)
+	}
+
+	h.Set("title")
+	if v := h.Get("title"); v != nil {
+		t.Fatal("expected no titles, got", v)
+	}
+}
@@ -0,0 +1,154 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package comment
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+)
+
+// PictureField is the name of the comment field holding base64-encoded pictures,
+// as in https://wiki.xiph.org/VorbisComment#Cover_art .
+const PictureField = "METADATA_BLOCK_PICTURE"
+
+// ErrPicture is the error used for a malformed picture block.
+var ErrPicture = errors.New("invalid picture block")
+
+// A PictureType is the kind of a picture, as in ID3v2's APIC frame.
+type PictureType uint32
+
+const (
+	PictureOther PictureType = iota
+	PictureFileIcon
+	PictureOtherFileIcon
+	PictureFrontCover
+	PictureBackCover
+	PictureLeaflet
+	PictureMedia
+	PictureLeadArtist
+	PictureArtist
+	PictureConductor
+	PictureBand
+	PictureComposer
+	PictureLyricist
+	PictureRecordingLocation
+	PictureDuringRecording
+	PictureDuringPerformance
+	PictureScreenCapture
+	PictureFish
+	PictureIllustration
+	PictureBandLogo
+	PicturePublisherLogo
+)
+
+// A Picture is a picture in the format of a FLAC PICTURE metadata block,
+// which is how pictures are carried in comments.
+type Picture struct {
+	Type PictureType
+	// MIMEType is the MIME type of Data, or "-->" if Data is a URL.
+	MIMEType    string
+	Description string
+	// Width and Height are in pixels,
+	// Depth is the bits per pixel,
+	// and Colors is the number of colors in an indexed picture, or 0.
+	Width  uint32
+	Height uint32
+	Depth  uint32
+	Colors uint32
+	Data   []byte
+}
+
+// MarshalBinary encodes p as a picture block.
+func (p *Picture) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, 32+len(p.MIMEType)+len(p.Description)+len(p.Data))
+	b = binary.BigEndian.AppendUint32(b, uint32(p.Type))
+	b = binary.BigEndian.AppendUint32(b, uint32(len(p.MIMEType)))
+	b = append(b, p.MIMEType...)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(p.Description)))
+	b = append(b, p.Description...)
+	b = binary.BigEndian.AppendUint32(b, p.Width)
+	b = binary.BigEndian.AppendUint32(b, p.Height)
+	b = binary.BigEndian.AppendUint32(b, p.Depth)
+	b = binary.BigEndian.AppendUint32(b, p.Colors)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(p.Data)))
+	b = append(b, p.Data...)
+	return b, nil
+}
+
+// UnmarshalBinary decodes a picture block into p.
+func (p *Picture) UnmarshalBinary(b []byte) error {
+	r := pictureReader{b: b}
+	q := Picture{
+		Type:        PictureType(r.uint32()),
+		MIMEType:    string(r.bytes()),
+		Description: string(r.bytes()),
+		Width:       r.uint32(),
+		Height:      r.uint32(),
+		Depth:       r.uint32(),
+		Colors:      r.uint32(),
+		Data:        append([]byte(nil), r.bytes()...),
+	}
+	if r.short {
+		return ErrPicture
+	}
+	*p = q
+	return nil
+}
+
+// A pictureReader reads the fields of a picture block.
+// After reading past the end of b, short is true, and reads return zero.
+type pictureReader struct {
+	b     []byte
+	short bool
+}
+
+func (r *pictureReader) uint32() uint32 {
+	if len(r.b) < 4 {
+		r.short = true
+		return 0
+	}
+	v := binary.BigEndian.Uint32(r.b)
+	r.b = r.b[4:]
+	return v
+}
+
+func (r *pictureReader) bytes() []byte {
+	l := r.uint32()
+	if uint32(len(r.b)) < l {
+		r.short = true
+		return nil
+	}
+	v := r.b[:l]
+	r.b = r.b[l:]
+	return v
+}
+
+// Pictures decodes the pictures in the comments.
+func (h *Header) Pictures() ([]Picture, error) {
+	var pics []Picture
+	for _, v := range h.Get(PictureField) {
+		b, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, err
+		}
+		var p Picture
+		err = p.UnmarshalBinary(b)
+		if err != nil {
+			return nil, err
+		}
+		pics = append(pics, p)
+	}
+	return pics, nil
+}
+
+// AddPicture adds a comment with the given picture.
+// Pictures can be removed with Set(PictureField).
+func (h *Header) AddPicture(p *Picture) error {
+	b, err := p.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	h.Add(PictureField, base64.StdEncoding.EncodeToString(b))
+	return nil
+}
@@ -0,0 +1,80 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package comment
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestPictureRoundTrip(t *testing.T) {
+	p := Picture{
+		Type:        PictureFrontCover,
+		MIMEType:    "image/png",
+		Description: "Front",
+		Width:       600,
+		Height:      600,
+		Depth:       24,
+		Data:        []byte("\x89PNG data"),
+	}
+	b, err := p.MarshalBinary()
+	if err != nil {
+		t.Fatal("unexpected MarshalBinary error:", err)
+	}
+	expect := []byte("\x00\x00\x00\x03\x00\x00\x00\x09image/png\x00\x00\x00\x05Front" +
+		"\x00\x00\x02\x58\x00\x00\x02\x58\x00\x00\x00\x18\x00\x00\x00\x00\x00\x00\x00\x09\x89PNG data")
+	if !bytes.Equal(b, expect) {
+		t.Fatalf("bytes are wrong:\n%q\nexpected\n%q", b, expect)
+	}
+
+	var got Picture
+	err = got.UnmarshalBinary(b)
+	if err != nil {
+		t.Fatal("unexpected UnmarshalBinary error:", err)
+	}
+	if !reflect.DeepEqual(got, p) {
+		t.Fatalf("expected %+v, got %+v", p, got)
+	}
+
+	for i := 0; i < len(b); i++ {
+		err = got.UnmarshalBinary(b[:i])
+		if err != ErrPicture {
+			t.Fatalf("expected ErrPicture for %d bytes, got %v", i, err)
+		}
+	}
+}
+
+func TestHeaderPictures(t *testing.T) {
+	h := Header{Comments: []string{"TITLE=a"}}
+	pics := []Picture{
+		{Type: PictureFrontCover, MIMEType: "image/jpeg", Data: []byte("front")},
+		{Type: PictureBackCover, MIMEType: "image/jpeg", Data: []byte("back")},
+	}
+	for i := range pics {
+		err := h.AddPicture(&pics[i])
+		if err != nil {
+			t.Fatal("unexpected AddPicture error:", err)
+		}
+	}
+
+	got, err := h.Pictures()
+	if err != nil {
+		t.Fatal("unexpected Pictures error:", err)
+	}
+	if !reflect.DeepEqual(got, pics) {
+		t.Fatalf("expected %+v, got %+v", pics, got)
+	}
+
+	h.Set(PictureField)
+	got, err = h.Pictures()
+	if err != nil || got != nil || len(h.Comments) != 1 {
+		t.Fatalf("pictures weren't removed: %v %v %q", got, err, h.Comments)
+	}
+
+	h.Add(PictureField, "not base64!")
+	_, err = h.Pictures()
+	if err == nil {
+		t.Fatal("expected an error for bad base64")
+	}
+}
@@ -4,7 +4,6 @@ package ogg
 
 import (
 	"bytes"
-	"encoding/binary"
 	"errors"
 	"io"
 	"strconv"
@@ -12,10 +11,28 @@ import (
 
 // A Decoder decodes an ogg stream page-by-page with its Decode method.
 type Decoder struct {
-	// buffer for packet lengths, to avoid allocating (mss is also the max per page)
-	lenbuf [mss]int
-	r      io.Reader
-	buf    [maxPageSize]byte
+	// buffers for packet lengths and slices, to avoid allocating (mss is also the max per page)
+	lenbuf  [mss]int
+	packets [mss][]byte
+	r       io.Reader
+	buf     [maxPageSize]byte
+
+	off   int64 // offset in the stream of the next byte to read
+	start int64 // offset of the current page's capture pattern
+
+	// the offset and size of the page last returned by Decode,
+	// and the bytes read outside of returned pages up to counted
+	pageOff  int64
+	pageSize int
+	skipped  int64
+	counted  int64
+
+	// A lenient Decoder pushes back the bytes of invalid pages after their capture patterns,
+	// to be read again before r, and collects the damage it skips until the next valid page.
+	lenient bool
+	pending []byte
+	damage  func(Damage)
+	hurt    *Damage
 }
 
 // NewDecoder creates an ogg Decoder.
@@ -23,6 +40,33 @@ func NewDecoder(r io.Reader) *Decoder {
 	return &Decoder{r: r}
 }
 
+// NewLenientDecoder creates an ogg Decoder that recovers from damage,
+// like libogg's ogg_sync_pageseek.
+// Instead of returning errors for invalid pages, its Decode method skips them,
+// searching for the next page from the byte after their capture patterns,
+// and it skips a truncated page at the end of the stream.
+// If damage isn't nil, it's called with each invalid page and each run of bytes outside of pages,
+// before the page that follows them is returned.
+func NewLenientDecoder(r io.Reader, damage func(Damage)) *Decoder {
+	return &Decoder{r: r, lenient: true, damage: damage}
+}
+
+// A Damage is a region of a physical stream that a lenient Decoder skipped,
+// from an invalid page or bytes outside of pages to the next capture pattern.
+type Damage struct {
+	// Offset is the position of the region in the stream, and Size is its length in bytes.
+	Offset int64
+	Size   int64
+	// Err is why the page at Offset is invalid: an ErrBadCrc, ErrBadSegs,
+	// or io.ErrUnexpectedEOF for a truncated page.
+	// It's nil if the region is only bytes outside of pages.
+	Err error
+	// Serial and Sequence are from the invalid page's header, if Err isn't nil,
+	// though they may be wrong if the damage is to the header.
+	Serial   uint32
+	Sequence uint32
+}
+
 // A Page represents a logical ogg page.
 type Page struct {
 	// Type is a bitmask of COP, BOS, and/or EOS.
@@ -31,10 +75,24 @@ type Page struct {
 	Serial uint32
 	// Granule is the granule position, whose meaning is dependent on the encapsulated codec.
 	Granule int64
+	// Sequence is the page sequence number within the logical stream.
+	Sequence uint32
+	// Version is the stream structure version, which is 0 in valid streams.
+	Version byte
+	// Crc is the page checksum as found in the page header.
+	Crc uint32
+	// Segments is the segment table of lacing values,
+	// from which the lengths of Packets are determined.
+	Segments []byte
 	// Packets are the raw packet data.
 	// If Type & COP != 0, the first element is
 	// a continuation of the previous page's last packet.
 	Packets [][]byte
+	// Continued is true if the last element of Packets is incomplete
+	// and continues on the next page.
+	// If Continued is true, Type & COP != 0, and there is only one element in Packets,
+	// no packet completes on this page and Granule should be -1.
+	Continued bool
 }
 
 // ErrBadSegs is the error used when trying to decode a page with a segment table size less than 1.
@@ -56,22 +114,146 @@ var oggs = []byte{'O', 'g', 'g', 'S'}
 // Decode reads from d's Reader to the next ogg page, then returns the decoded Page or an error.
 // The error may be io.EOF if that's what the Reader returned.
 //
-// The buffer underlying the returned Page's Packets' bytes is owned by the Decoder.
-// It may be overwritten by subsequent calls to Decode.
+// The returned Page's Packets and Segments, and the bytes underlying them, are owned by the Decoder.
+// They may be overwritten by subsequent calls to Decode.
+// Once the Decoder has read a page, Decode doesn't allocate unless a lenient Decoder finds damage.
 //
 // It is safe to call Decode concurrently on distinct Decoders if their Readers are distinct.
 // Otherwise, the behavior is undefined.
 func (d *Decoder) Decode() (Page, error) {
+	var p Page
+	var err error
+	if d.lenient {
+		p, err = d.decodeLenient()
+	} else {
+		err = d.capture()
+		if err == nil {
+			p, err = d.decodePage()
+		}
+	}
+
+	skipped := d.off - d.counted
+	if err == nil {
+		d.pageOff = d.start
+		d.pageSize = int(d.off - d.start)
+		skipped -= int64(d.pageSize)
+	}
+	d.skipped += skipped
+	d.counted = d.off
+	return p, err
+}
+
+// Offset returns the position in the stream of the page last returned by Decode,
+// counting from where the Decoder began reading.
+func (d *Decoder) Offset() int64 {
+	return d.pageOff
+}
+
+// Size returns the length in bytes of the page last returned by Decode,
+// from its capture pattern to the end of its last packet.
+func (d *Decoder) Size() int {
+	return d.pageSize
+}
+
+// Skipped returns the number of bytes Decode has read that weren't in the pages it returned,
+// such as junk before capture patterns, pages for which it returned errors,
+// and the invalid pages skipped by a lenient Decoder.
+func (d *Decoder) Skipped() int64 {
+	return d.skipped
+}
+
+// reset makes d read from r, which is at the given offset in the stream.
+func (d *Decoder) reset(r io.Reader, off int64) {
+	d.r = r
+	d.off = off
+	d.counted = off
+	d.pending = nil
+}
+
+func (d *Decoder) decodeLenient() (Page, error) {
+	for {
+		begin := d.off
+		err := d.capture()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			d.skip(begin, d.off)
+			d.report()
+			return Page{}, io.EOF
+		}
+		if err != nil {
+			return Page{}, err
+		}
+		d.skip(begin, d.start)
+		d.report()
+
+		p, err := d.decodePage()
+		if err == nil {
+			return p, nil
+		}
+		if err != io.ErrUnexpectedEOF && !isDamage(err) {
+			return Page{}, err
+		}
+
+		d.skip(d.start, d.start+1)
+		d.hurt.Err = err
+		d.hurt.Serial = byteOrder.Uint32(d.buf[14:18])
+		d.hurt.Sequence = byteOrder.Uint32(d.buf[18:22])
+
+		// Search the rest of the invalid page for the next one.
+		d.pending = append(append([]byte(nil), d.buf[1:d.off-d.start]...), d.pending...)
+		d.off = d.start + 1
+	}
+}
+
+// skip adds the bytes from off to end to the current damaged region, or begins one.
+func (d *Decoder) skip(off, end int64) {
+	if end <= off {
+		return
+	}
+	if d.hurt == nil {
+		d.hurt = &Damage{Offset: off}
+	}
+	d.hurt.Size = end - d.hurt.Offset
+}
+
+// report reports the current damaged region, if there is one.
+func (d *Decoder) report() {
+	if d.hurt != nil && d.damage != nil {
+		d.damage(*d.hurt)
+	}
+	d.hurt = nil
+}
+
+// readFull reads len(b) bytes into b like io.ReadFull,
+// taking any pending bytes before those of d.r.
+func (d *Decoder) readFull(b []byte) (int, error) {
+	n := copy(b, d.pending)
+	d.pending = d.pending[n:]
+	var err error
+	if n < len(b) {
+		var m int
+		m, err = io.ReadFull(d.r, b[n:])
+		if err == io.EOF && n > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		n += m
+	}
+	d.off += int64(n)
+	return n, err
+}
+
+// capture reads through the next capture pattern and the rest of the page header.
+func (d *Decoder) capture() error {
 	hbuf := d.buf[0:headsz]
 	b := 0
 	for {
-		_, err := io.ReadFull(d.r, hbuf[b:])
+		_, err := d.readFull(hbuf[b:])
 		if err != nil {
-			return Page{}, err
+			return err
 		}
 
 		i := bytes.Index(hbuf, oggs)
 		if i == 0 {
+			d.start = d.off - headsz
 			break
 		}
 
@@ -90,9 +272,14 @@ func (d *Decoder) Decode() (Page, error) {
 			b = copy(hbuf, hbuf[i:])
 		}
 	}
+	return nil
+}
 
+// decodePage decodes the page whose header capture read.
+func (d *Decoder) decodePage() (Page, error) {
+	hbuf := d.buf[0:headsz]
 	var h pageHeader
-	_ = binary.Read(bytes.NewBuffer(hbuf), byteOrder, &h)
+	h.parse(hbuf)
 
 	if h.Nsegs < 1 {
 		return Page{}, ErrBadSegs
@@ -100,9 +287,9 @@ func (d *Decoder) Decode() (Page, error) {
 
 	nsegs := int(h.Nsegs)
 	segtbl := d.buf[headsz : headsz+nsegs]
-	_, err := io.ReadFull(d.r, segtbl)
+	_, err := d.readFull(segtbl)
 	if err != nil {
-		return Page{}, err
+		return Page{}, d.truncated(err)
 	}
 
 	// A page can contain multiple packets; record their lengths from the table
@@ -124,9 +311,9 @@ func (d *Decoder) Decode() (Page, error) {
 	}
 
 	payload := d.buf[headsz+nsegs : headsz+nsegs+payloadlen]
-	_, err = io.ReadFull(d.r, payload)
+	_, err = d.readFull(payload)
 	if err != nil {
-		return Page{}, err
+		return Page{}, d.truncated(err)
 	}
 
 	page := d.buf[0 : headsz+nsegs+payloadlen]
@@ -136,16 +323,37 @@ func (d *Decoder) Decode() (Page, error) {
 	page[24] = 0
 	page[25] = 0
 	crc := crc32(page)
+	byteOrder.PutUint32(page[22:], h.Crc)
 	if crc != h.Crc {
 		return Page{}, ErrBadCrc{h.Crc, crc}
 	}
 
-	packets := make([][]byte, len(packetlens))
+	packets := d.packets[:len(packetlens)]
 	s := 0
 	for i, l := range packetlens {
 		packets[i] = payload[s : s+l]
 		s += l
 	}
 
-	return Page{h.HeaderType, h.Serial, h.Granule, packets}, nil
+	return Page{
+		Type:      h.HeaderType,
+		Serial:    h.Serial,
+		Granule:   h.Granule,
+		Sequence:  h.Page,
+		Version:   h.StreamVersion,
+		Crc:       h.Crc,
+		Segments:  segtbl,
+		Packets:   packets,
+		Continued: more,
+	}, nil
+}
+
+// truncated returns io.ErrUnexpectedEOF for io.EOF if d is lenient,
+// since a page after its capture pattern is truncated even if nothing more was read.
+// A strict Decoder returns io.EOF as it's read, to end streams cut after a page header.
+func (d *Decoder) truncated(err error) error {
+	if err == io.EOF && d.lenient {
+		return io.ErrUnexpectedEOF
+	}
+	return err
 }
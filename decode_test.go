@@ -6,6 +6,7 @@ import (
 	"bytes"
 	"io"
 	"math/rand"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -248,6 +249,98 @@ func TestBadSegs(t *testing.T) {
 	}
 }
 
+func TestLenientDecode(t *testing.T) {
+	var good, bad bytes.Buffer
+	e := NewEncoder(1, &good)
+	for i := 0; i < 4; i++ {
+		err := e.Encode(int64(i), [][]byte{[]byte("hello")})
+		if err != nil {
+			t.Fatal("unexpected Encode error:", err)
+		}
+	}
+	pages := bytes.SplitAfter(good.Bytes(), []byte("hello"))
+
+	// A page whose header claims more data than it has,
+	// so the next page's bytes are read as its payload.
+	err := NewEncoder(2, &bad).Encode(0, [][]byte{make([]byte, 100)})
+	if err != nil {
+		t.Fatal("unexpected Encode error:", err)
+	}
+	short := bad.Bytes()[:40]
+
+	var stream []byte
+	stream = append(stream, pages[0]...)
+	stream = append(stream, short...)
+	stream = append(stream, pages[1]...)
+	stream = append(stream, "junk"...)
+	stream = append(stream, pages[2]...)
+	stream = append(stream, pages[3][:len(pages[3])-1]...)
+
+	var damage []Damage
+	d := NewLenientDecoder(bytes.NewReader(stream), func(dmg Damage) {
+		damage = append(damage, dmg)
+	})
+	var granules []int64
+	for {
+		p, err := d.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal("unexpected Decode error:", err)
+		}
+		granules = append(granules, p.Granule)
+	}
+	if !reflect.DeepEqual(granules, []int64{0, 1, 2}) {
+		t.Fatal("expected pages 0, 1, and 2, got", granules)
+	}
+
+	n := int64(len(pages[0]))
+	if d.Skipped() != 40+4+n-1 {
+		t.Fatalf("expected %d bytes skipped, got %d", 40+4+n-1, d.Skipped())
+	}
+	expect := []Damage{
+		{Offset: n, Size: 40, Err: ErrBadCrc{}, Serial: 2},
+		{Offset: 2*n + 40, Size: 4},
+		{Offset: 3*n + 44, Size: n - 1, Err: io.ErrUnexpectedEOF, Serial: 1, Sequence: 3},
+	}
+	if len(damage) != len(expect) {
+		t.Fatalf("expected %d damaged regions, got %+v", len(expect), damage)
+	}
+	for i, x := range expect {
+		if _, ok := x.Err.(ErrBadCrc); ok {
+			if _, ok := damage[i].Err.(ErrBadCrc); !ok {
+				t.Fatal("expected ErrBadCrc, got", damage[i].Err)
+			}
+			damage[i].Err = x.Err
+		}
+		if !reflect.DeepEqual(damage[i], x) {
+			t.Fatalf("expected %+v, got %+v", x, damage[i])
+		}
+	}
+
+	// Streams cut right after a page header, and right after its segment table.
+	for _, cut := range []int{headsz, headsz + 1} {
+		stream = append(append([]byte(nil), pages[0]...), pages[3][:cut]...)
+		damage = nil
+		d = NewLenientDecoder(bytes.NewReader(stream), func(dmg Damage) {
+			damage = append(damage, dmg)
+		})
+		_, err = d.Decode()
+		if err != nil {
+			t.Fatal("unexpected Decode error:", err)
+		}
+		_, err = d.Decode()
+		if err != io.EOF {
+			t.Fatal("expected EOF, got", err)
+		}
+		x := Damage{Offset: n, Size: int64(cut), Err: io.ErrUnexpectedEOF, Serial: 1, Sequence: 3}
+		if len(damage) != 1 || !reflect.DeepEqual(damage[0], x) {
+			t.Fatalf("expected %+v, got %+v", x, damage)
+		}
+	}
+}
+
 func TestSyncDecode(t *testing.T) {
 	var b bytes.Buffer
 	for i := 0; i < headsz-1; i++ {
@@ -304,6 +397,79 @@ func TestSyncDecode(t *testing.T) {
 	}
 }
 
+func TestDecodeOffsets(t *testing.T) {
+	var b bytes.Buffer
+	e := NewEncoder(1, &b)
+	for i := 0; i < 3; i++ {
+		err := e.Encode(int64(i), [][]byte{bytes.Repeat([]byte("x"), i*100)})
+		if err != nil {
+			t.Fatal("unexpected Encode error:", err)
+		}
+	}
+	var sizes []int
+	for d := NewDecoder(bytes.NewReader(b.Bytes())); ; {
+		_, err := d.Decode()
+		if err != nil {
+			break
+		}
+		sizes = append(sizes, d.Size())
+	}
+	if len(sizes) != 3 || sizes[0]+sizes[1]+sizes[2] != b.Len() {
+		t.Fatalf("expected 3 pages in %d bytes, got sizes %v", b.Len(), sizes)
+	}
+	pages := [][]byte{
+		b.Bytes()[:sizes[0]],
+		b.Bytes()[sizes[0] : sizes[0]+sizes[1]],
+		b.Bytes()[sizes[0]+sizes[1]:],
+	}
+	pages[1][30]++ // break the CRC
+
+	var stream []byte
+	stream = append(stream, "0123456789"...)
+	stream = append(stream, pages[0]...)
+	stream = append(stream, pages[1]...)
+	stream = append(stream, "xx"...)
+	stream = append(stream, pages[2]...)
+	stream = append(stream, "tail"...)
+
+	d := NewDecoder(bytes.NewReader(stream))
+	expect := []struct {
+		granule int64
+		err     bool
+		offset  int64
+		size    int
+		skipped int64
+	}{
+		{0, false, 10, sizes[0], 10},
+		{0, true, 10, sizes[0], int64(10 + sizes[1])},
+		{2, false, int64(10 + sizes[0] + sizes[1] + 2), sizes[2], int64(12 + sizes[1])},
+	}
+	for _, x := range expect {
+		p, err := d.Decode()
+		if x.err {
+			if _, ok := err.(ErrBadCrc); !ok {
+				t.Fatal("expected ErrBadCrc, got", err)
+			}
+		} else if err != nil {
+			t.Fatal("unexpected Decode error:", err)
+		} else if p.Granule != x.granule {
+			t.Fatalf("expected granule %d, got %d", x.granule, p.Granule)
+		}
+		if d.Offset() != x.offset || d.Size() != x.size || d.Skipped() != x.skipped {
+			t.Fatalf("expected offset %d, size %d, and %d skipped, got %d, %d, and %d",
+				x.offset, x.size, x.skipped, d.Offset(), d.Size(), d.Skipped())
+		}
+	}
+
+	_, err := d.Decode()
+	if err != io.ErrUnexpectedEOF {
+		t.Fatal("expected io.ErrUnexpectedEOF, got", err)
+	}
+	if d.Skipped() != int64(16+sizes[1]) {
+		t.Fatalf("expected %d bytes skipped, got %d", 16+sizes[1], d.Skipped())
+	}
+}
+
 func TestLongDecode(t *testing.T) {
 	var b bytes.Buffer
 	e := NewEncoder(1, &b)
@@ -513,3 +679,131 @@ func TestEvenLongerMultipacketDecode(t *testing.T) {
 		t.Fatalf("packet is wrong:\n\t%x\nvs\n\t%x\n", p3.Packets[0], junk.Bytes()[start:])
 	}
 }
+
+func TestHeaderFieldsDecode(t *testing.T) {
+	var b bytes.Buffer
+	e := NewEncoder(1, &b)
+
+	err := e.EncodeBOS(2, [][]byte{[]byte("hello")})
+	if err != nil {
+		t.Fatal("unexpected EncodeBOS error:", err)
+	}
+	err = e.Encode(7, [][]byte{[]byte("there"), nil})
+	if err != nil {
+		t.Fatal("unexpected Encode error:", err)
+	}
+
+	d := NewDecoder(bytes.NewReader(b.Bytes()))
+
+	p, err := d.Decode()
+	if err != nil {
+		t.Fatal("unexpected Decode error:", err)
+	}
+	if p.Sequence != 0 {
+		t.Fatal("expected sequence 0, got", p.Sequence)
+	}
+	if p.Version != 0 {
+		t.Fatal("expected version 0, got", p.Version)
+	}
+	if p.Crc != 0x1e2edf7e {
+		t.Fatalf("expected crc 1e2edf7e, got %x", p.Crc)
+	}
+	if !bytes.Equal(p.Segments, []byte{5}) {
+		t.Fatalf("segments != expected:\n%x\n%x", p.Segments, []byte{5})
+	}
+
+	p, err = d.Decode()
+	if err != nil {
+		t.Fatal("unexpected Decode error:", err)
+	}
+	if p.Sequence != 1 {
+		t.Fatal("expected sequence 1, got", p.Sequence)
+	}
+	if !bytes.Equal(p.Segments, []byte{5, 0}) {
+		t.Fatalf("segments != expected:\n%x\n%x", p.Segments, []byte{5, 0})
+	}
+}
+
+func TestContinuedDecode(t *testing.T) {
+	var b bytes.Buffer
+	e := NewEncoder(1, &b)
+
+	var junk bytes.Buffer
+	for i := 0; i < mps+50; i++ {
+		junk.WriteByte('x')
+	}
+
+	err := e.Encode(2, [][]byte{[]byte("hello"), junk.Bytes()})
+	if err != nil {
+		t.Fatal("unexpected Encode error:", err)
+	}
+
+	d := NewDecoder(&b)
+	p1, err := d.Decode()
+	if err != nil {
+		t.Fatal("unexpected Decode error:", err)
+	}
+	if !p1.Continued {
+		t.Fatal("expected first page to be continued")
+	}
+
+	p2, err := d.Decode()
+	if err != nil {
+		t.Fatal("unexpected Decode error:", err)
+	}
+	if p2.Continued {
+		t.Fatal("expected second page to be complete")
+	}
+}
+
+// testStream returns n encoded pages of a few packets each.
+func testStream(tb testing.TB, n int) []byte {
+	var b bytes.Buffer
+	e := NewEncoder(1, &b)
+	packets := [][]byte{make([]byte, 1000), make([]byte, 3000), make([]byte, 10)}
+	for i := 0; i < n; i++ {
+		err := e.Encode(int64(i), packets)
+		if err != nil {
+			tb.Fatal("unexpected Encode error:", err)
+		}
+	}
+	return b.Bytes()
+}
+
+// decodeLoop decodes the next page of stream with d, which reads r,
+// starting over at the end of the stream.
+func decodeLoop(tb testing.TB, d *Decoder, r *bytes.Reader, stream []byte) {
+	_, err := d.Decode()
+	if err == io.EOF {
+		r.Reset(stream)
+		_, err = d.Decode()
+	}
+	if err != nil {
+		tb.Fatal("unexpected Decode error:", err)
+	}
+}
+
+func TestDecodeAllocs(t *testing.T) {
+	stream := testStream(t, 10)
+	r := bytes.NewReader(stream)
+	d := NewDecoder(r)
+	allocs := testing.AllocsPerRun(100, func() {
+		decodeLoop(t, d, r, stream)
+	})
+	if allocs != 0 {
+		t.Fatal("expected no allocations per page, got", allocs)
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	const n = 100
+	stream := testStream(b, n)
+	r := bytes.NewReader(stream)
+	d := NewDecoder(r)
+	b.SetBytes(int64(len(stream) / n))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		decodeLoop(b, d, r, stream)
+	}
+}
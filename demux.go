@@ -0,0 +1,168 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package ogg
+
+import (
+	"io"
+)
+
+// A Demuxer separates a physical ogg stream into its logical streams.
+// It reads packets with a PacketReader, holding each one
+// until it is read from its LogicalStream.
+type Demuxer struct {
+	r       *PacketReader
+	streams map[uint32]*LogicalStream // streams that haven't ended
+	fresh   []*LogicalStream          // streams not yet returned by NextStream
+	seen    bool                      // whether any stream has begun
+	link    int
+	err     error
+}
+
+// A LogicalStream is one of a Demuxer's logical streams.
+type LogicalStream struct {
+	// Serial is the bitstream serial number.
+	Serial uint32
+	// Link is the position in a chained physical stream of the group
+	// of logical streams that this stream belongs to, starting at 0.
+	Link int
+
+	d       *Demuxer
+	queue   []demuxed
+	ended   bool
+	dropped bool
+}
+
+// demuxed is a packet, or an error concerning the packet that would've been there.
+type demuxed struct {
+	p   Packet
+	err error
+}
+
+// NewDemuxer creates a Demuxer that reads from r.
+func NewDemuxer(r io.Reader) *Demuxer {
+	d := &Demuxer{
+		r:       NewPacketReader(r),
+		streams: make(map[uint32]*LogicalStream),
+	}
+	d.r.ended = d.end
+	return d
+}
+
+// NextStream returns the next logical stream to begin,
+// reading from the physical stream as necessary.
+// Streams usually begin with their BOS page,
+// but if reading starts in the middle of a stream, it begins with its first packet read.
+// After the last stream in a chain link ends, the next stream to begin
+// is the first of the next link.
+//
+// The error is io.EOF if the physical stream ended without another logical stream.
+func (d *Demuxer) NextStream() (*LogicalStream, error) {
+	for len(d.fresh) == 0 {
+		if d.err != nil {
+			return nil, d.err
+		}
+		d.read()
+	}
+
+	s := d.fresh[0]
+	d.fresh[0] = nil
+	d.fresh = d.fresh[1:]
+	return s, nil
+}
+
+// ReadPacket returns the stream's next packet,
+// reading from the physical stream as necessary.
+// Packets for other streams are held until they are read from their own streams.
+//
+// The error is io.EOF after the stream's EOS packet has been returned,
+// or io.ErrUnexpectedEOF if the physical stream ended first.
+// An ErrMissingContinuation error concerns only this stream, and reading may continue.
+func (s *LogicalStream) ReadPacket() (Packet, error) {
+	for len(s.queue) == 0 {
+		if s.ended {
+			return Packet{}, io.EOF
+		}
+		if err := s.d.err; err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return Packet{}, err
+		}
+		s.d.read()
+	}
+
+	x := s.queue[0]
+	s.queue[0] = demuxed{}
+	s.queue = s.queue[1:]
+	return x.p, x.err
+}
+
+// Drop discards the stream's held packets, and any that are read for it later.
+// Streams that will never be read should be dropped so that the Demuxer doesn't hold their packets.
+func (s *LogicalStream) Drop() {
+	s.dropped = true
+	s.queue = nil
+}
+
+// FindStream returns the next logical stream to begin with a BOS packet whose data match accepts,
+// along with that packet. Streams that don't match are dropped.
+//
+// The error is io.EOF if the physical stream ended without a matching stream.
+func (d *Demuxer) FindStream(match func(first []byte) bool) (*LogicalStream, Packet, error) {
+	for {
+		s, err := d.NextStream()
+		if err != nil {
+			return nil, Packet{}, err
+		}
+		p, err := s.ReadPacket()
+		if err != nil {
+			return nil, Packet{}, err
+		}
+		if p.BOS && match(p.Data) {
+			return s, p, nil
+		}
+		s.Drop()
+	}
+}
+
+// read routes a packet to its stream, or records the error from reading it.
+func (d *Demuxer) read() {
+	p, err := d.r.ReadPacket()
+	if mc, ok := err.(ErrMissingContinuation); ok {
+		if s := d.streams[mc.Serial]; s != nil && !s.dropped {
+			s.queue = append(s.queue, demuxed{err: err})
+		}
+		return
+	}
+	if err != nil {
+		d.err = err
+		return
+	}
+
+	s := d.streams[p.Serial]
+	if s == nil {
+		if len(d.streams) == 0 && d.seen {
+			d.link++
+		}
+		d.seen = true
+
+		s = &LogicalStream{Serial: p.Serial, Link: d.link, d: d}
+		d.streams[p.Serial] = s
+		d.fresh = append(d.fresh, s)
+	}
+
+	if !s.dropped {
+		s.queue = append(s.queue, demuxed{p: p})
+	}
+	if p.EOS {
+		d.end(p.Serial)
+	}
+}
+
+// end ends a stream, after its EOS page.
+func (d *Demuxer) end(serial uint32) {
+	if s := d.streams[serial]; s != nil {
+		s.ended = true
+		delete(d.streams, serial)
+	}
+}
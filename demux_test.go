@@ -0,0 +1,219 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package ogg
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestChainedDemuxer(t *testing.T) {
+	var b bytes.Buffer
+	e1 := NewEncoder(1, &b)
+	e2 := NewEncoder(2, &b)
+	e3 := NewEncoder(3, &b)
+
+	steps := []struct {
+		e    *Encoder
+		f    func(*Encoder, int64, [][]byte) error
+		data string
+	}{
+		{e1, (*Encoder).EncodeBOS, "1 head"},
+		{e2, (*Encoder).EncodeBOS, "2 head"},
+		{e1, (*Encoder).Encode, "1 data"},
+		{e2, (*Encoder).Encode, "2 data"},
+		{e2, (*Encoder).EncodeEOS, "2 end"},
+		{e1, (*Encoder).EncodeEOS, "1 end"},
+		{e3, (*Encoder).EncodeBOS, "3 head"},
+		{e3, (*Encoder).EncodeEOS, "3 end"},
+	}
+	for _, s := range steps {
+		err := s.f(s.e, 0, [][]byte{[]byte(s.data)})
+		if err != nil {
+			t.Fatal("unexpected encoding error:", err)
+		}
+	}
+
+	d := NewDemuxer(&b)
+	s1, err := d.NextStream()
+	if err != nil {
+		t.Fatal("unexpected NextStream error:", err)
+	}
+	s2, err := d.NextStream()
+	if err != nil {
+		t.Fatal("unexpected NextStream error:", err)
+	}
+	if s1.Serial != 1 || s2.Serial != 2 || s1.Link != 0 || s2.Link != 0 {
+		t.Fatalf("streams are wrong: %d in link %d, %d in link %d", s1.Serial, s1.Link, s2.Serial, s2.Link)
+	}
+
+	// Read stream 2 entirely first, leaving stream 1's packets held.
+	for _, x := range []string{"2 head", "2 data", "2 end"} {
+		p, err := s2.ReadPacket()
+		if err != nil {
+			t.Fatal("unexpected ReadPacket error:", err)
+		}
+		if string(p.Data) != x {
+			t.Fatalf("expected %q, got %q", x, p.Data)
+		}
+	}
+	_, err = s2.ReadPacket()
+	if err != io.EOF {
+		t.Fatal("expected EOF, got:", err)
+	}
+
+	s3, err := d.NextStream()
+	if err != nil {
+		t.Fatal("unexpected NextStream error:", err)
+	}
+	if s3.Serial != 3 || s3.Link != 1 {
+		t.Fatalf("stream is wrong: %d in link %d", s3.Serial, s3.Link)
+	}
+	s3.Drop()
+
+	for _, x := range []string{"1 head", "1 data", "1 end"} {
+		p, err := s1.ReadPacket()
+		if err != nil {
+			t.Fatal("unexpected ReadPacket error:", err)
+		}
+		if string(p.Data) != x {
+			t.Fatalf("expected %q, got %q", x, p.Data)
+		}
+	}
+	_, err = s1.ReadPacket()
+	if err != io.EOF {
+		t.Fatal("expected EOF, got:", err)
+	}
+
+	_, err = d.NextStream()
+	if err != io.EOF {
+		t.Fatal("expected EOF, got:", err)
+	}
+}
+
+func TestTruncatedDemuxer(t *testing.T) {
+	var b bytes.Buffer
+	e := NewEncoder(1, &b)
+	err := e.EncodeBOS(0, [][]byte{[]byte("head")})
+	if err != nil {
+		t.Fatal("unexpected EncodeBOS error:", err)
+	}
+
+	d := NewDemuxer(&b)
+	s, err := d.NextStream()
+	if err != nil {
+		t.Fatal("unexpected NextStream error:", err)
+	}
+	_, err = s.ReadPacket()
+	if err != nil {
+		t.Fatal("unexpected ReadPacket error:", err)
+	}
+	_, err = s.ReadPacket()
+	if err != io.ErrUnexpectedEOF {
+		t.Fatal("expected ErrUnexpectedEOF, got:", err)
+	}
+}
+
+func TestEmptyEOSDemuxer(t *testing.T) {
+	var b bytes.Buffer
+	e1 := NewEncoder(1, &b)
+	e2 := NewEncoder(2, &b)
+	long := make([]byte, mss)
+
+	// Stream 1's EOS page only continues a packet, which it doesn't complete.
+	pages := []struct {
+		e *Encoder
+		p Page
+	}{
+		{e1, Page{Type: BOS, Packets: [][]byte{[]byte("1 head")}}},
+		{e1, Page{Packets: [][]byte{[]byte("1 data"), long}, Continued: true}},
+		{e1, Page{Type: COP | EOS, Packets: [][]byte{long}, Continued: true}},
+		{e2, Page{Type: BOS, Packets: [][]byte{[]byte("2 head")}}},
+		{e2, Page{Type: EOS, Packets: [][]byte{[]byte("2 end")}}},
+	}
+	for _, x := range pages {
+		err := x.e.WritePage(x.p)
+		if err != nil {
+			t.Fatal("unexpected WritePage error:", err)
+		}
+	}
+
+	d := NewDemuxer(&b)
+	s1, err := d.NextStream()
+	if err != nil {
+		t.Fatal("unexpected NextStream error:", err)
+	}
+	for _, x := range []string{"1 head", "1 data"} {
+		p, err := s1.ReadPacket()
+		if err != nil {
+			t.Fatal("unexpected ReadPacket error:", err)
+		}
+		if string(p.Data) != x || p.EOS {
+			t.Fatalf("expected %q without EOS, got %q, EOS %v", x, p.Data, p.EOS)
+		}
+	}
+	_, err = s1.ReadPacket()
+	if err != io.EOF {
+		t.Fatal("expected EOF, got:", err)
+	}
+
+	s2, err := d.NextStream()
+	if err != nil {
+		t.Fatal("unexpected NextStream error:", err)
+	}
+	if s2.Serial != 2 || s2.Link != 1 {
+		t.Fatalf("stream is wrong: %d in link %d", s2.Serial, s2.Link)
+	}
+}
+
+func TestFindStream(t *testing.T) {
+	var b bytes.Buffer
+	e1 := NewEncoder(1, &b)
+	e2 := NewEncoder(2, &b)
+
+	steps := []struct {
+		e    *Encoder
+		f    func(*Encoder, int64, [][]byte) error
+		data string
+	}{
+		{e1, (*Encoder).EncodeBOS, "other head"},
+		{e2, (*Encoder).EncodeBOS, "wanted head"},
+		{e1, (*Encoder).Encode, "other data"},
+		{e2, (*Encoder).Encode, "wanted data"},
+		{e1, (*Encoder).EncodeEOS, "other end"},
+		{e2, (*Encoder).EncodeEOS, "wanted end"},
+	}
+	for _, s := range steps {
+		err := s.f(s.e, 0, [][]byte{[]byte(s.data)})
+		if err != nil {
+			t.Fatal("unexpected encoding error:", err)
+		}
+	}
+	wanted := func(first []byte) bool {
+		return bytes.HasPrefix(first, []byte("wanted"))
+	}
+
+	d := NewDemuxer(bytes.NewReader(b.Bytes()))
+	s, p, err := d.FindStream(wanted)
+	if err != nil {
+		t.Fatal("unexpected FindStream error:", err)
+	}
+	if s.Serial != 2 || !p.BOS || string(p.Data) != "wanted head" {
+		t.Fatalf("expected stream 2 with its head, got stream %d with %+v", s.Serial, p)
+	}
+	for _, x := range []string{"wanted data", "wanted end"} {
+		p, err := s.ReadPacket()
+		if err != nil {
+			t.Fatal("unexpected ReadPacket error:", err)
+		}
+		if string(p.Data) != x {
+			t.Fatalf("expected %q, got %q", x, p.Data)
+		}
+	}
+
+	_, _, err = d.FindStream(wanted)
+	if err != io.EOF {
+		t.Fatal("expected EOF, got:", err)
+	}
+}
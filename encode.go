@@ -3,8 +3,7 @@
 package ogg
 
 import (
-	"bytes"
-	"encoding/binary"
+	"errors"
 	"io"
 )
 
@@ -37,6 +36,10 @@ func NewEncoder(id uint32, w io.Writer) *Encoder {
 // using the provided granule position.
 // If the packets are larger than can fit in a page, the payload is split into multiple
 // pages with the continuation-of-packet flag set.
+// Only the first page is marked beginning-of-stream.
+// The granule position applies to all of the packets, as if each were given it
+// with libogg's ogg_stream_packetin, so the pages on which any of them complete have it,
+// and the others have -1.
 // Packets can be empty or nil, in which one segment of size 0 is encoded.
 func (w *Encoder) EncodeBOS(granule int64, packets [][]byte) error {
 	if len(packets) == 0 {
@@ -49,6 +52,8 @@ func (w *Encoder) EncodeBOS(granule int64, packets [][]byte) error {
 // using the provided granule position.
 // If the packet is larger than can fit in a page, it is split into multiple
 // pages with the continuation-of-packet flag set.
+// As with EncodeBOS, only the pages on which packets complete have the granule position;
+// the others have -1.
 // Packets can be empty or nil, in which one segment of size 0 is encoded.
 func (w *Encoder) Encode(granule int64, packets [][]byte) error {
 	if len(packets) == 0 {
@@ -58,6 +63,7 @@ func (w *Encoder) Encode(granule int64, packets [][]byte) error {
 }
 
 // EncodeEOS writes an end-of-stream packet to the ogg stream.
+// If the packets span multiple pages, only the last is marked end-of-stream.
 // Packets can be empty or nil, in which one segment of size 0 is encoded.
 func (w *Encoder) EncodeEOS(granule int64, packets [][]byte) error {
 	if len(packets) == 0 {
@@ -66,58 +72,119 @@ func (w *Encoder) EncodeEOS(granule int64, packets [][]byte) error {
 	return w.writePackets(EOS, granule, packets)
 }
 
-func (w *Encoder) writePackets(kind byte, granule int64, packets [][]byte) error {
+// ErrPageLayout is the error used by WritePage when a Page's Packets can't form a valid page.
+var ErrPageLayout = errors.New("packets don't fit the page layout")
+
+// WritePage writes a page with the Type, Granule, Packets, and Continued of p,
+// such as one from a Decoder, in w's logical stream.
+// The serial number, sequence number, and CRC are w's own,
+// and the segment table is computed from Packets and Continued, ignoring p.Segments.
+// If Continued, the last packet's length must be a multiple of 255.
+// Unlike the other methods, WritePage never splits the packets across pages.
+func (w *Encoder) WritePage(p Page) error {
+	segtbl := w.buf[headsz:headsz]
+	for i, packet := range p.Packets {
+		s255s := len(packet) / mss
+		rem := len(packet) % mss
+		open := p.Continued && i == len(p.Packets)-1
+		if open && (rem != 0 || s255s == 0) {
+			return ErrPageLayout
+		}
+		nsegs := s255s
+		if !open {
+			nsegs++
+		}
+		if len(segtbl)+nsegs > mss {
+			return ErrPageLayout
+		}
+		for j := 0; j < s255s; j++ {
+			segtbl = append(segtbl, mss)
+		}
+		if !open {
+			segtbl = append(segtbl, byte(rem))
+		}
+	}
+	if len(segtbl) == 0 {
+		return ErrPageLayout
+	}
+
 	h := pageHeader{
 		OggS:       [4]byte{'O', 'g', 'g', 'S'},
-		HeaderType: kind,
+		HeaderType: p.Type,
+		Granule:    p.Granule,
 		Serial:     w.serial,
-		Granule:    granule,
 	}
+	return w.writePage(&h, segtbl, payload{packets: p.Packets})
+}
 
-	// Write the lacing values before filling in their quantity
-	segtbl, car, cdr := w.segmentize(payload{packets[0], packets[1:], nil})
-	err := w.writePage(&h, segtbl, car)
-	if err != nil {
-		return err
+func (w *Encoder) writePackets(kind byte, granule int64, packets [][]byte) error {
+	h := pageHeader{
+		OggS:   [4]byte{'O', 'g', 'g', 'S'},
+		Serial: w.serial,
 	}
 
-	h.HeaderType |= COP
-	for len(cdr.leftover) > 0 {
-		segtbl, car, cdr = w.segmentize(cdr)
-		err = w.writePage(&h, segtbl, car)
+	// Only the first page is BOS and only the last is EOS.
+	// Per the RFC, pages on which no packet completes get granule -1.
+	pay := payload{packets[0], packets[1:], nil}
+	h.HeaderType = kind &^ EOS
+	for {
+		segtbl, car, cdr, more := w.segmentize(pay)
+		h.Granule = -1
+		if completes(segtbl) {
+			h.Granule = granule
+		}
+		if !more {
+			h.HeaderType |= kind & EOS
+		}
+
+		err := w.writePage(&h, segtbl, car)
 		if err != nil {
 			return err
 		}
-	}
+		if !more {
+			return nil
+		}
 
-	return nil
+		h.HeaderType = 0
+		if segtbl[len(segtbl)-1] == mss {
+			h.HeaderType = COP
+		}
+		pay = cdr
+	}
 }
 
 func (w *Encoder) writePage(h *pageHeader, segtbl []byte, pay payload) error {
 	h.Page = w.page
 	w.page++
 	h.Nsegs = byte(len(segtbl))
-	hb := bytes.NewBuffer(w.buf[0:0:cap(w.buf)])
-	_ = binary.Write(hb, byteOrder, h)
-
-	// segtbl is already written in the buffer,
-	// but the writer needs to move along anyhow
-	hb.Write(segtbl)
+	h.Crc = 0
+	h.put(w.buf[:headsz])
 
-	hb.Write(pay.leftover)
+	// segtbl is usually already in the buffer, in which case this copy is a no-op
+	n := headsz + copy(w.buf[headsz:], segtbl)
+	n += copy(w.buf[n:], pay.leftover)
 	for _, p := range pay.packets {
-		hb.Write(p)
+		n += copy(w.buf[n:], p)
 	}
-	hb.Write(pay.rightover)
+	n += copy(w.buf[n:], pay.rightover)
 
-	bb := hb.Bytes()
-	crc := crc32(bb)
-	_ = binary.Write(bytes.NewBuffer(bb[22:22:26]), byteOrder, crc)
+	page := w.buf[:n]
+	byteOrder.PutUint32(page[22:26], crc32(page))
 
-	_, err := hb.WriteTo(w.w)
+	_, err := w.w.Write(page)
 	return err
 }
 
+// completes reports whether a packet ends in the segment table.
+func completes(segtbl []byte) bool {
+	for _, l := range segtbl {
+		if l < mss {
+			return true
+		}
+	}
+	return false
+}
+
 // payload represents a potentially-split group of packets.
 // For the "left" portion of a split,
 // rightover is the beginning portion of the *last* packet,
@@ -144,8 +211,10 @@ type payload struct {
 // provided in payload, starting with leftover (if any).
 // It returns the segment table (sized appropriately),
 // the payload to write with the segment table in the current page,
-// and any leftover payload that remains due to not fitting in a page.
-func (w *Encoder) segmentize(pay payload) ([]byte, payload, payload) {
+// any leftover payload that remains due to not fitting in a page,
+// and whether there was any leftover.
+// The leftover may be empty, but still need a page for a packet's final lacing value.
+func (w *Encoder) segmentize(pay payload) ([]byte, payload, payload, bool) {
 	segtbl := w.buf[headsz : headsz+mss]
 	i := 0
 
@@ -163,7 +232,7 @@ func (w *Encoder) segmentize(pay payload) ([]byte, payload, payload) {
 		leftStart := len(pay.leftover) - (s255s * mss) - rem
 		good := payload{pay.leftover[0:leftStart], nil, nil}
 		bad := payload{pay.leftover[leftStart:], pay.packets, nil}
-		return segtbl, good, bad
+		return segtbl, good, bad, true
 	}
 
 	// Now loop through the rest and track if we need to split
@@ -182,11 +251,11 @@ func (w *Encoder) segmentize(pay payload) ([]byte, payload, payload) {
 			right := len(pay.packets[p]) - (s255s * mss) - rem
 			good := payload{pay.leftover, pay.packets[0:p], pay.packets[p][0:right]}
 			bad := payload{pay.packets[p][right:], pay.packets[p+1:], nil}
-			return segtbl, good, bad
+			return segtbl, good, bad, true
 		}
 	}
 
 	good := pay
 	bad := payload{}
-	return segtbl[0:i], good, bad
+	return segtbl[0:i], good, bad, false
 }
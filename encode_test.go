@@ -5,6 +5,7 @@ package ogg
 import (
 	"bytes"
 	"io"
+	"os"
 	"testing"
 )
 
@@ -191,10 +192,10 @@ func TestLongEncode(t *testing.T) {
 		'O', 'g', 'g', 'S',
 		0,
 		0,
-		2, 0, 0, 0, 0, 0, 0, 0,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, // no packet completes
 		1, 0, 0, 0,
 		0, 0, 0, 0,
-		0xee, 0xb2, 0x0b, 0xca, // crc
+		0xf6, 0x57, 0x1d, 0x19, // crc
 		255,
 	}
 
@@ -206,16 +207,198 @@ func TestLongEncode(t *testing.T) {
 		'O', 'g', 'g', 'S',
 		0,
 		COP,
-		2, 0, 0, 0, 0, 0, 0, 0,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
 		1, 0, 0, 0,
 		1, 0, 0, 0,
-		0x17, 0x0d, 0xe6, 0xe6, // crc
+		0x0f, 0xe8, 0xf0, 0x35, // crc
 		255,
 	}
 
 	if !bytes.Equal(bb[maxPageSize:maxPageSize+headsz], expect2) {
 		t.Fatalf("bytes != expected:\n%x\n%x", bb[maxPageSize:maxPageSize+headsz], expect2)
 	}
+
+	expect3 := []byte{
+		'O', 'g', 'g', 'S',
+		0,
+		COP,
+		2, 0, 0, 0, 0, 0, 0, 0,
+		1, 0, 0, 0,
+		2, 0, 0, 0,
+		0x36, 0xd5, 0x2d, 0x47, // crc
+		3,
+		255, 255, 54, // segment table
+	}
+
+	p3 := bb[maxPageSize*2 : maxPageSize*2+headsz+3]
+	if !bytes.Equal(p3, expect3) {
+		t.Fatalf("bytes != expected:\n%x\n%x", p3, expect3)
+	}
+}
+
+// The files in testdata were written by libogg.
+// Their pages hold only whole packets, so encoding each page's packets
+// with its granule position should reproduce them exactly.
+func TestLiboggEncode(t *testing.T) {
+	for _, name := range []string{"testdata/vorbis.ogg", "testdata/opus.opus"} {
+		src, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal("unexpected ReadFile error:", err)
+		}
+
+		var b bytes.Buffer
+		var e *Encoder
+		for _, p := range decodeAll(t, bytes.NewReader(src)) {
+			if e == nil {
+				e = NewEncoder(p.Serial, &b)
+			}
+			encode := e.Encode
+			if p.Type&BOS != 0 {
+				encode = e.EncodeBOS
+			} else if p.Type&EOS != 0 {
+				encode = e.EncodeEOS
+			}
+			err := encode(p.Granule, p.Packets)
+			if err != nil {
+				t.Fatal("unexpected encoding error:", err)
+			}
+		}
+
+		got := b.Bytes()
+		for i := range src {
+			if i >= len(got) || got[i] != src[i] {
+				t.Fatalf("%s: output differs from libogg's at byte %d of %d", name, i, len(src))
+			}
+		}
+		if len(got) != len(src) {
+			t.Fatalf("%s: output is %d bytes, expected %d", name, len(got), len(src))
+		}
+	}
+}
+
+// testdata/split.ogg was written by libogg from these steps,
+// each step's packets given one granule position and then flushed,
+// as described in testdata/split.c.
+func TestLiboggSplitEncode(t *testing.T) {
+	src, err := os.ReadFile("testdata/split.ogg")
+	if err != nil {
+		t.Fatal("unexpected ReadFile error:", err)
+	}
+
+	var b bytes.Buffer
+	e := NewEncoder(0x5eed, &b)
+	steps := []struct {
+		encode  func(int64, [][]byte) error
+		granule int64
+		sizes   []int
+	}{
+		{e.EncodeBOS, 0, []int{30}},
+		{e.Encode, 0, []int{70000}},
+		{e.Encode, 1000, []int{100, 200, mps, 50}},
+		{e.Encode, 2000, []int{mps}},
+		{e.EncodeEOS, 3000, []int{300, 70000}},
+	}
+	n := 0
+	for _, x := range steps {
+		var packets [][]byte
+		for _, size := range x.sizes {
+			p := make([]byte, size)
+			for k := range p {
+				p[k] = byte((n*31 + k) % 251)
+			}
+			packets = append(packets, p)
+			n++
+		}
+		err := x.encode(x.granule, packets)
+		if err != nil {
+			t.Fatal("unexpected encoding error:", err)
+		}
+	}
+
+	got := b.Bytes()
+	for i := range src {
+		if i >= len(got) || got[i] != src[i] {
+			t.Fatalf("output differs from libogg's at byte %d of %d", i, len(src))
+		}
+	}
+	if len(got) != len(src) {
+		t.Fatalf("output is %d bytes, expected %d", len(got), len(src))
+	}
+}
+
+func TestSplitFlagsEncode(t *testing.T) {
+	var b bytes.Buffer
+	e := NewEncoder(1, &b)
+
+	long := bytes.Repeat([]byte{'x'}, mps*2+10)
+	err := e.EncodeBOS(3, [][]byte{long})
+	if err != nil {
+		t.Fatal("unexpected EncodeBOS error:", err)
+	}
+	err = e.EncodeEOS(9, [][]byte{long})
+	if err != nil {
+		t.Fatal("unexpected EncodeEOS error:", err)
+	}
+
+	expect := []struct {
+		typ     byte
+		granule int64
+	}{
+		{BOS, -1},
+		{COP, -1},
+		{COP, 3},
+		{0, -1},
+		{COP, -1},
+		{COP | EOS, 9},
+	}
+
+	pages := decodeAll(t, &b)
+	if len(pages) != len(expect) {
+		t.Fatalf("expected %d pages, got %d", len(expect), len(pages))
+	}
+	for i, x := range expect {
+		if pages[i].Type != x.typ || pages[i].Granule != x.granule {
+			t.Fatalf("page %d is wrong: type %d, granule %d", i, pages[i].Type, pages[i].Granule)
+		}
+	}
+}
+
+func TestExactSplitEncode(t *testing.T) {
+	var b bytes.Buffer
+	e := NewEncoder(1, &b)
+
+	// This packet fills a page's segment table with 255s,
+	// so its terminating 0 goes on the next page.
+	exact := bytes.Repeat([]byte{'x'}, mps)
+	err := e.Encode(2, [][]byte{exact, []byte("hello")})
+	if err != nil {
+		t.Fatal("unexpected Encode error:", err)
+	}
+
+	// This packet ends exactly at the end of a page's segment table,
+	// so the next page starts a new packet.
+	full := bytes.Repeat([]byte{'x'}, mps-mss+10)
+	err = e.Encode(3, [][]byte{full, []byte("there")})
+	if err != nil {
+		t.Fatal("unexpected Encode error:", err)
+	}
+
+	pages := decodeAll(t, &b)
+	if len(pages) != 4 {
+		t.Fatalf("expected 4 pages, got %d", len(pages))
+	}
+	if pages[1].Type != COP || !bytes.Equal(pages[1].Segments, []byte{0, 5}) {
+		t.Fatalf("page is wrong: type %d, segments %v", pages[1].Type, pages[1].Segments)
+	}
+	if pages[0].Granule != -1 || pages[1].Granule != 2 {
+		t.Fatalf("pages are wrong: granules %d and %d", pages[0].Granule, pages[1].Granule)
+	}
+	if pages[2].Granule != 3 || pages[2].Continued {
+		t.Fatalf("page is wrong: granule %d, continued %v", pages[2].Granule, pages[2].Continued)
+	}
+	if pages[3].Type != 0 || pages[3].Granule != 3 {
+		t.Fatalf("page is wrong: type %d, granule %d", pages[3].Type, pages[3].Granule)
+	}
 }
 
 type limitedWriter struct {
@@ -250,3 +433,85 @@ func TestShortWrites(t *testing.T) {
 		t.Fatal("expected ErrClosedPipe, got:", err)
 	}
 }
+
+func TestWritePage(t *testing.T) {
+	var b bytes.Buffer
+	e := NewEncoder(1, &b)
+	err := e.EncodeBOS(0, [][]byte{[]byte("head")})
+	if err != nil {
+		t.Fatal("unexpected EncodeBOS error:", err)
+	}
+	err = e.Encode(5, [][]byte{[]byte("a"), bytes.Repeat([]byte("b"), mps+mss*3), nil, []byte("c")})
+	if err != nil {
+		t.Fatal("unexpected Encode error:", err)
+	}
+	err = e.EncodeEOS(6, [][]byte{bytes.Repeat([]byte("d"), mss)})
+	if err != nil {
+		t.Fatal("unexpected EncodeEOS error:", err)
+	}
+
+	// Copying every page should give the same stream.
+	var c bytes.Buffer
+	e2 := NewEncoder(1, &c)
+	for _, p := range decodeAll(t, bytes.NewReader(b.Bytes())) {
+		err = e2.WritePage(p)
+		if err != nil {
+			t.Fatal("unexpected WritePage error:", err)
+		}
+	}
+	if !bytes.Equal(b.Bytes(), c.Bytes()) {
+		t.Fatal("copied stream differs")
+	}
+
+	bad := []Page{
+		{},
+		{Packets: [][]byte{[]byte("x")}, Continued: true},
+		{Packets: [][]byte{nil}, Continued: true},
+		{Packets: [][]byte{make([]byte, mps)}},
+	}
+	for _, p := range bad {
+		err = e2.WritePage(p)
+		if err != ErrPageLayout {
+			t.Fatalf("expected ErrPageLayout for %d packets, got %v", len(p.Packets), err)
+		}
+	}
+}
+
+func TestEncodeAllocs(t *testing.T) {
+	e := NewEncoder(1, io.Discard)
+	packets := [][]byte{make([]byte, 1000), make([]byte, mps)}
+	allocs := testing.AllocsPerRun(100, func() {
+		err := e.Encode(1, packets)
+		if err != nil {
+			t.Fatal("unexpected Encode error:", err)
+		}
+	})
+	if allocs != 0 {
+		t.Fatal("expected no allocations per Encode, got", allocs)
+	}
+
+	p := Page{Granule: 2, Packets: packets[:1]}
+	allocs = testing.AllocsPerRun(100, func() {
+		err := e.WritePage(p)
+		if err != nil {
+			t.Fatal("unexpected WritePage error:", err)
+		}
+	})
+	if allocs != 0 {
+		t.Fatal("expected no allocations per WritePage, got", allocs)
+	}
+}
+
+func BenchmarkEncode(b *testing.B) {
+	e := NewEncoder(1, io.Discard)
+	packets := [][]byte{make([]byte, 1000), make([]byte, 3000), make([]byte, 10)}
+	b.SetBytes(4010)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := e.Encode(int64(i), packets)
+		if err != nil {
+			b.Fatal("unexpected Encode error:", err)
+		}
+	}
+}
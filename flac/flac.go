@@ -0,0 +1,380 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+/*
+Package flac implements the Ogg mapping of FLAC as defined in
+https://xiph.org/flac/ogg_mapping.html ,
+and conversion between it and native FLAC files, without decoding audio.
+*/
+package flac
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"mccoy.space/g/ogg"
+	"mccoy.space/g/ogg/comment"
+)
+
+var (
+	mappingMagic = []byte("\x7fFLAC")
+	nativeMagic  = []byte("fLaC")
+)
+
+// The version of the Ogg mapping written by Writer.
+const (
+	MajorVersion = 1
+	MinorVersion = 0
+)
+
+// ErrMagic is the error used when a header lacks its magic signature.
+var ErrMagic = errors.New("missing FLAC signature")
+
+// ErrShort is the error used when a header or metadata block is truncated.
+var ErrShort = errors.New("truncated FLAC header")
+
+// ErrVersion is the error used for an unsupported mapping version.
+var ErrVersion = errors.New("unsupported Ogg FLAC mapping version")
+
+// ErrStreamInfo is the error used for a STREAMINFO block with invalid fields,
+// or when it isn't the first metadata block.
+var ErrStreamInfo = errors.New("invalid FLAC STREAMINFO")
+
+// ErrMetadata is the error used for a malformed metadata block.
+var ErrMetadata = errors.New("invalid FLAC metadata block")
+
+// ErrNoStream is the error used by NewReader when there is no FLAC stream.
+var ErrNoStream = errors.New("no FLAC stream")
+
+// A BlockType is the type of a metadata block.
+type BlockType uint8
+
+const (
+	StreamInfoBlock BlockType = iota
+	PaddingBlock
+	ApplicationBlock
+	SeekTableBlock
+	CommentBlock
+	CueSheetBlock
+	PictureBlock
+)
+
+// A MetadataBlock is a metadata block other than STREAMINFO.
+type MetadataBlock struct {
+	Type BlockType
+	Data []byte
+}
+
+// NewCommentBlock creates a VORBIS_COMMENT metadata block from h.
+func NewCommentBlock(h *comment.Header) MetadataBlock {
+	return MetadataBlock{CommentBlock, h.Append(nil)}
+}
+
+// Comment parses a VORBIS_COMMENT metadata block.
+func (m *MetadataBlock) Comment() (comment.Header, error) {
+	if m.Type != CommentBlock {
+		return comment.Header{}, ErrMetadata
+	}
+	h, _, err := comment.Parse(m.Data)
+	return h, err
+}
+
+// NewPictureBlock creates a PICTURE metadata block from p.
+func NewPictureBlock(p *comment.Picture) (MetadataBlock, error) {
+	b, err := p.MarshalBinary()
+	return MetadataBlock{PictureBlock, b}, err
+}
+
+// Picture parses a PICTURE metadata block.
+func (m *MetadataBlock) Picture() (comment.Picture, error) {
+	var p comment.Picture
+	if m.Type != PictureBlock {
+		return p, ErrMetadata
+	}
+	err := p.UnmarshalBinary(m.Data)
+	return p, err
+}
+
+// appendBlock appends a metadata block with its header to b.
+func appendBlock(b []byte, t BlockType, data []byte, last bool) []byte {
+	h := byte(t)
+	if last {
+		h |= 0x80
+	}
+	n := len(data)
+	b = append(b, h, byte(n>>16), byte(n>>8), byte(n))
+	return append(b, data...)
+}
+
+// parseBlockHeader parses a metadata block header.
+func parseBlockHeader(b []byte) (t BlockType, n int, last bool, err error) {
+	if len(b) < 4 {
+		return 0, 0, false, ErrShort
+	}
+	t = BlockType(b[0] & 0x7f)
+	if t == 127 {
+		return 0, 0, false, ErrMetadata
+	}
+	n = int(b[1])<<16 | int(b[2])<<8 | int(b[3])
+	return t, n, b[0]&0x80 != 0, nil
+}
+
+// A StreamInfo is the STREAMINFO metadata block, which describes the whole stream.
+type StreamInfo struct {
+	// The block sizes are in samples per channel.
+	MinBlockSize uint16
+	MaxBlockSize uint16
+	// The frame sizes are in bytes, or 0 if unknown.
+	MinFrameSize uint32
+	MaxFrameSize uint32
+	// SampleRate is the sample rate in Hz, which is also the rate of granule positions.
+	SampleRate    uint32
+	Channels      uint8
+	BitsPerSample uint8
+	// TotalSamples is the number of samples per channel, or 0 if unknown.
+	TotalSamples uint64
+	// MD5 is the MD5 signature of the unencoded audio, or zero if unknown.
+	MD5 [16]byte
+}
+
+const streamInfoSize = 34
+
+// Validate checks the ranges of si's fields.
+func (si *StreamInfo) Validate() error {
+	if si.MinBlockSize < 16 || si.MaxBlockSize < si.MinBlockSize ||
+		si.MinFrameSize >= 1<<24 || si.MaxFrameSize >= 1<<24 ||
+		si.SampleRate == 0 || si.SampleRate >= 1<<20 ||
+		si.Channels < 1 || si.Channels > 8 ||
+		si.BitsPerSample < 4 || si.BitsPerSample > 32 ||
+		si.TotalSamples >= 1<<36 {
+		return ErrStreamInfo
+	}
+	return nil
+}
+
+// MarshalBinary encodes si as the data of a STREAMINFO block, after validating it.
+func (si *StreamInfo) MarshalBinary() ([]byte, error) {
+	err := si.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, streamInfoSize)
+	binary.BigEndian.PutUint16(b[0:], si.MinBlockSize)
+	binary.BigEndian.PutUint16(b[2:], si.MaxBlockSize)
+	put24(b[4:], si.MinFrameSize)
+	put24(b[7:], si.MaxFrameSize)
+	x := uint64(si.SampleRate)<<44 | uint64(si.Channels-1)<<41 | uint64(si.BitsPerSample-1)<<36 | si.TotalSamples
+	binary.BigEndian.PutUint64(b[10:], x)
+	copy(b[18:], si.MD5[:])
+	return b, nil
+}
+
+// UnmarshalBinary decodes the data of a STREAMINFO block into si, and validates it.
+func (si *StreamInfo) UnmarshalBinary(b []byte) error {
+	if len(b) < streamInfoSize {
+		return ErrShort
+	}
+	x := binary.BigEndian.Uint64(b[10:])
+	*si = StreamInfo{
+		MinBlockSize:  binary.BigEndian.Uint16(b[0:]),
+		MaxBlockSize:  binary.BigEndian.Uint16(b[2:]),
+		MinFrameSize:  get24(b[4:]),
+		MaxFrameSize:  get24(b[7:]),
+		SampleRate:    uint32(x >> 44),
+		Channels:      uint8(x>>41&0x07) + 1,
+		BitsPerSample: uint8(x>>36&0x1f) + 1,
+		TotalSamples:  x & (1<<36 - 1),
+	}
+	copy(si.MD5[:], b[18:])
+	return si.Validate()
+}
+
+func put24(b []byte, v uint32) {
+	b[0], b[1], b[2] = byte(v>>16), byte(v>>8), byte(v)
+}
+
+func get24(b []byte) uint32 {
+	return uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+}
+
+// A Reader reads the audio frames of an Ogg FLAC stream.
+type Reader struct {
+	// Serial is the FLAC stream's bitstream serial number.
+	Serial uint32
+	// The mapping version of the stream.
+	MajorVersion uint8
+	MinorVersion uint8
+	StreamInfo   StreamInfo
+	// Metadata is the metadata blocks following the STREAMINFO.
+	Metadata []MetadataBlock
+
+	s *ogg.LogicalStream
+}
+
+// NewReader reads the headers of the first FLAC stream in r.
+// Other logical streams in r are ignored.
+func NewReader(r io.Reader) (*Reader, error) {
+	d := ogg.NewDemuxer(r)
+	s, p, err := d.FindStream(func(first []byte) bool {
+		return bytes.HasPrefix(first, mappingMagic)
+	})
+	if err == io.EOF {
+		return nil, ErrNoStream
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fr := &Reader{Serial: s.Serial, s: s}
+	last, err := fr.parseFirst(p.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	for !last {
+		p, err = s.ReadPacket()
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		var t BlockType
+		var n int
+		t, n, last, err = parseBlockHeader(p.Data)
+		if err != nil {
+			return nil, err
+		}
+		if t == StreamInfoBlock || n != len(p.Data)-4 {
+			return nil, ErrMetadata
+		}
+		fr.Metadata = append(fr.Metadata, MetadataBlock{t, append([]byte(nil), p.Data[4:]...)})
+	}
+	return fr, nil
+}
+
+// parseFirst parses the first packet of the stream,
+// returning whether its STREAMINFO is the last metadata block.
+func (r *Reader) parseFirst(b []byte) (bool, error) {
+	if len(b) < 13 {
+		return false, ErrShort
+	}
+	r.MajorVersion, r.MinorVersion = b[5], b[6]
+	if r.MajorVersion != MajorVersion {
+		return false, ErrVersion
+	}
+	if !bytes.Equal(b[9:13], nativeMagic) {
+		return false, ErrMagic
+	}
+
+	t, n, last, err := parseBlockHeader(b[13:])
+	if err != nil {
+		return false, err
+	}
+	if t != StreamInfoBlock || n != streamInfoSize {
+		return false, ErrStreamInfo
+	}
+	return last, r.StreamInfo.UnmarshalBinary(b[17:])
+}
+
+// ReadPacket returns the next audio packet, which is one frame.
+// The error is io.EOF after the last packet.
+func (r *Reader) ReadPacket() (ogg.Packet, error) {
+	return r.s.ReadPacket()
+}
+
+// A Writer writes an Ogg FLAC stream.
+type Writer struct {
+	pw      *ogg.PacketWriter
+	samples int64
+}
+
+// NewWriter creates a Writer of an Ogg FLAC stream with the given serial,
+// writing the headers immediately.
+// The mapping requires a VORBIS_COMMENT block to follow the STREAMINFO,
+// so any in metadata are written first, and an empty one is added if there are none.
+func NewWriter(w io.Writer, serial uint32, si *StreamInfo, metadata []MetadataBlock, policy ogg.PagePolicy) (*Writer, error) {
+	sib, err := si.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks []MetadataBlock
+	for _, m := range metadata {
+		if m.Type == CommentBlock {
+			blocks = append(blocks, m)
+		}
+	}
+	if len(blocks) == 0 {
+		blocks = append(blocks, NewCommentBlock(&comment.Header{}))
+	}
+	for _, m := range metadata {
+		if m.Type == StreamInfoBlock {
+			return nil, ErrStreamInfo
+		}
+		if m.Type != CommentBlock {
+			blocks = append(blocks, m)
+		}
+	}
+	// Check the blocks before writing any, so an error leaves no partial stream.
+	for _, m := range blocks {
+		if len(m.Data) >= 1<<24 {
+			return nil, ErrMetadata
+		}
+	}
+
+	first := append([]byte(nil), mappingMagic...)
+	first = append(first, MajorVersion, MinorVersion)
+	first = binary.BigEndian.AppendUint16(first, uint16(len(blocks)))
+	first = append(first, nativeMagic...)
+	first = appendBlock(first, StreamInfoBlock, sib, false)
+
+	pw := ogg.NewPacketWriter(serial, w, policy)
+	err = pw.WritePacket(0, first)
+	if err != nil {
+		return nil, err
+	}
+	for i, m := range blocks {
+		err = pw.WritePacket(0, appendBlock(nil, m.Type, m.Data, i == len(blocks)-1))
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = pw.Flush()
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{pw: pw}, nil
+}
+
+// WriteFrame writes an audio frame,
+// using the block size in its header to find its granule position.
+func (w *Writer) WriteFrame(frame []byte) error {
+	h, _, err := ParseFrameHeader(frame)
+	if err != nil {
+		return err
+	}
+	w.samples += int64(h.BlockSize)
+	return w.pw.WritePacket(w.samples, frame)
+}
+
+// WriteLastFrame writes an audio frame like WriteFrame, and ends the stream with it.
+// It does not close the underlying Writer.
+func (w *Writer) WriteLastFrame(frame []byte) error {
+	h, _, err := ParseFrameHeader(frame)
+	if err != nil {
+		return err
+	}
+	w.samples += int64(h.BlockSize)
+	return w.pw.WriteLastPacket(w.samples, frame)
+}
+
+// Close writes any buffered frames and ends the stream.
+// If no frames are buffered, the end-of-stream page has an empty packet,
+// so WriteLastFrame is preferable.
+// It does not close the underlying Writer.
+func (w *Writer) Close() error {
+	return w.pw.Close()
+}
@@ -0,0 +1,238 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package flac
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"mccoy.space/g/ogg"
+	"mccoy.space/g/ogg/comment"
+)
+
+var testInfo = StreamInfo{
+	MinBlockSize:  100,
+	MaxBlockSize:  4096,
+	SampleRate:    44100,
+	Channels:      2,
+	BitsPerSample: 16,
+	TotalSamples:  9*4096 + 100,
+	MD5:           [16]byte{1, 2, 3},
+}
+
+// testFrame returns a frame with the given number and a header for 4096 samples,
+// or the given block size if it's smaller, and random contents.
+func testFrame(num byte, blockSize int) []byte {
+	f := []byte{0xff, 0xf8, 0xc9, 0x18, num}
+	if blockSize < 4096 {
+		f[2] = 0x69
+		f = append(f, byte(blockSize-1))
+	}
+	f = append(f, crc8(f))
+
+	// Another frame's header in the middle shouldn't be mistaken for the next frame.
+	header := append([]byte(nil), f...)
+	for i := 0; i < 500; i++ {
+		f = append(f, byte(rand.Intn(256)))
+	}
+	f = append(f, header...)
+	f = append(f, 0xff, 0xf8, 0xff, 0xf9)
+
+	crc := crc16(0, f)
+	return append(f, byte(crc>>8), byte(crc))
+}
+
+func testNative(t *testing.T) []byte {
+	sib, err := testInfo.MarshalBinary()
+	if err != nil {
+		t.Fatal("unexpected MarshalBinary error:", err)
+	}
+	c := comment.Header{Vendor: "test", Comments: []string{"TITLE=Song"}}
+
+	b := append([]byte(nil), nativeMagic...)
+	b = appendBlock(b, StreamInfoBlock, sib, false)
+	b = appendBlock(b, CommentBlock, c.Append(nil), false)
+	b = appendBlock(b, PaddingBlock, make([]byte, 100), true)
+	for i := 0; i < 9; i++ {
+		b = append(b, testFrame(byte(i), 4096)...)
+	}
+	return append(b, testFrame(9, 100)...)
+}
+
+func TestStreamInfoRoundTrip(t *testing.T) {
+	b, err := testInfo.MarshalBinary()
+	if err != nil {
+		t.Fatal("unexpected MarshalBinary error:", err)
+	}
+	if len(b) != 34 {
+		t.Fatal("expected 34 bytes, got", len(b))
+	}
+
+	var si StreamInfo
+	err = si.UnmarshalBinary(b)
+	if err != nil {
+		t.Fatal("unexpected UnmarshalBinary error:", err)
+	}
+	if si != testInfo {
+		t.Fatalf("expected %+v, got %+v", testInfo, si)
+	}
+
+	si.Channels = 9
+	_, err = si.MarshalBinary()
+	if err != ErrStreamInfo {
+		t.Fatal("expected ErrStreamInfo, got", err)
+	}
+}
+
+func TestParseFrameHeader(t *testing.T) {
+	tests := []struct {
+		b []byte
+		h FrameHeader
+	}{
+		{[]byte{0xff, 0xf8, 0xc9, 0x18, 0x05}, FrameHeader{BlockSize: 4096, SampleRate: 44100, Channels: 2, BitsPerSample: 16, Number: 5}},
+		{[]byte{0xff, 0xf9, 0x7d, 0x98, 0xc4, 0x80, 0x12, 0x34, 0x56, 0x78},
+			FrameHeader{VariableBlockSize: true, BlockSize: 0x1235, SampleRate: 0x5678, Channels: 2, BitsPerSample: 16, Number: 0x100}},
+		{[]byte{0xff, 0xf8, 0x10, 0x00, 0x00}, FrameHeader{BlockSize: 192, Channels: 1}},
+	}
+	for _, x := range tests {
+		b := append(x.b, crc8(x.b))
+		h, n, err := ParseFrameHeader(b)
+		if err != nil {
+			t.Fatalf("unexpected error for % x: %v", b, err)
+		}
+		if h != x.h || n != len(b) {
+			t.Fatalf("expected %+v in %d bytes, got %+v in %d", x.h, len(b), h, n)
+		}
+
+		b[len(b)-1]++
+		_, _, err = ParseFrameHeader(b)
+		if err != ErrFrameCRC {
+			t.Fatal("expected ErrFrameCRC, got", err)
+		}
+	}
+
+	_, _, err := ParseFrameHeader([]byte{0xff, 0xf8, 0x0f, 0x18, 0x00, 0x00})
+	if err != ErrFrameHeader {
+		t.Fatal("expected ErrFrameHeader, got", err)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	native := testNative(t)
+
+	var o bytes.Buffer
+	err := ToOgg(&o, bytes.NewReader(native), 7)
+	if err != nil {
+		t.Fatal("unexpected ToOgg error:", err)
+	}
+
+	// The last page's granule position is the total samples.
+	d := ogg.NewDecoder(bytes.NewReader(o.Bytes()))
+	var last ogg.Page
+	for {
+		p, err := d.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal("unexpected Decode error:", err)
+		}
+		last = p
+	}
+	if last.Type&ogg.EOS == 0 || uint64(last.Granule) != testInfo.TotalSamples {
+		t.Fatalf("expected an EOS page at granule %d, got %+v", testInfo.TotalSamples, last)
+	}
+
+	r, err := NewReader(bytes.NewReader(o.Bytes()))
+	if err != nil {
+		t.Fatal("unexpected NewReader error:", err)
+	}
+	if r.Serial != 7 || r.MajorVersion != 1 || r.StreamInfo != testInfo {
+		t.Fatalf("headers are wrong: %d %d %+v", r.Serial, r.MajorVersion, r.StreamInfo)
+	}
+	if len(r.Metadata) != 2 || r.Metadata[0].Type != CommentBlock || r.Metadata[1].Type != PaddingBlock {
+		t.Fatalf("metadata is wrong: %+v", r.Metadata)
+	}
+	c, err := r.Metadata[0].Comment()
+	if err != nil {
+		t.Fatal("unexpected Comment error:", err)
+	}
+	if !reflect.DeepEqual(c.Get("title"), []string{"Song"}) {
+		t.Fatal("comment is wrong:", c)
+	}
+
+	var n bytes.Buffer
+	err = ToNative(&n, bytes.NewReader(o.Bytes()))
+	if err != nil {
+		t.Fatal("unexpected ToNative error:", err)
+	}
+	if !bytes.Equal(n.Bytes(), native) {
+		t.Fatal("round trip doesn't match the original")
+	}
+}
+
+func TestConvertBadFrame(t *testing.T) {
+	native := testNative(t)
+	native[len(native)-100]++
+
+	err := ToOgg(io.Discard, bytes.NewReader(native), 7)
+	if err != ErrFrameCRC {
+		t.Fatal("expected ErrFrameCRC, got", err)
+	}
+}
+
+func TestWriterAddsComment(t *testing.T) {
+	var b bytes.Buffer
+	w, err := NewWriter(&b, 1, &testInfo, []MetadataBlock{{Type: ApplicationBlock, Data: []byte("test")}}, ogg.PagePolicy{})
+	if err != nil {
+		t.Fatal("unexpected NewWriter error:", err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatal("unexpected Close error:", err)
+	}
+
+	r, err := NewReader(&b)
+	if err != nil {
+		t.Fatal("unexpected NewReader error:", err)
+	}
+	if len(r.Metadata) != 2 || r.Metadata[0].Type != CommentBlock || r.Metadata[1].Type != ApplicationBlock {
+		t.Fatalf("metadata is wrong: %+v", r.Metadata)
+	}
+}
+
+func TestWriterBigBlock(t *testing.T) {
+	var b bytes.Buffer
+	big := MetadataBlock{Type: PaddingBlock, Data: make([]byte, 1<<24)}
+	_, err := NewWriter(&b, 1, &testInfo, []MetadataBlock{big}, ogg.PagePolicy{})
+	if err != ErrMetadata {
+		t.Fatal("expected ErrMetadata, got", err)
+	}
+	if b.Len() != 0 {
+		t.Fatalf("expected nothing written, got %d bytes", b.Len())
+	}
+}
+
+func TestPictureBlock(t *testing.T) {
+	pic := comment.Picture{Type: comment.PictureFrontCover, MIMEType: "image/png", Data: []byte("png")}
+	m, err := NewPictureBlock(&pic)
+	if err != nil {
+		t.Fatal("unexpected NewPictureBlock error:", err)
+	}
+	got, err := m.Picture()
+	if err != nil {
+		t.Fatal("unexpected Picture error:", err)
+	}
+	if !reflect.DeepEqual(got, pic) {
+		t.Fatalf("expected %+v, got %+v", pic, got)
+	}
+
+	m.Type = PaddingBlock
+	_, err = m.Picture()
+	if err != ErrMetadata {
+		t.Fatal("expected ErrMetadata, got", err)
+	}
+}
@@ -0,0 +1,203 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package flac
+
+import "errors"
+
+// ErrSync is the error used when a frame doesn't begin with the frame sync code.
+var ErrSync = errors.New("missing FLAC frame sync code")
+
+// ErrFrameHeader is the error used for a frame header with reserved or invalid values.
+var ErrFrameHeader = errors.New("invalid FLAC frame header")
+
+// ErrFrameCRC is the error used when a frame or its header fails its CRC check.
+var ErrFrameCRC = errors.New("FLAC frame CRC mismatch")
+
+// A FrameHeader is the header of a FLAC audio frame.
+type FrameHeader struct {
+	// VariableBlockSize is whether Number counts samples rather than frames.
+	VariableBlockSize bool
+	// BlockSize is the number of samples in the frame, per channel.
+	BlockSize int
+	// SampleRate is the sample rate in Hz, or 0 if it's given by the STREAMINFO.
+	SampleRate uint32
+	// Channels is the number of channels.
+	Channels uint8
+	// BitsPerSample is the sample size, or 0 if it's given by the STREAMINFO.
+	BitsPerSample uint8
+	// Number is the frame's number, or its first sample's number if VariableBlockSize.
+	Number uint64
+}
+
+var (
+	sampleRates = [...]uint32{0, 88200, 176400, 192000, 8000, 16000, 22050, 24000, 32000, 44100, 48000, 96000}
+	sampleSizes = [...]uint8{0, 8, 12, 0, 16, 20, 24, 32}
+)
+
+// ParseFrameHeader parses the frame header at the beginning of b,
+// checking its CRC-8, and returns it with its length.
+func ParseFrameHeader(b []byte) (FrameHeader, int, error) {
+	if len(b) < 2 || b[0] != 0xff || b[1]&0xfe != 0xf8 {
+		return FrameHeader{}, 0, ErrSync
+	}
+	if len(b) < 6 {
+		return FrameHeader{}, 0, ErrShort
+	}
+
+	h := FrameHeader{VariableBlockSize: b[1]&1 == 1}
+	bsCode := b[2] >> 4
+	rateCode := b[2] & 0x0f
+	chans := b[3] >> 4
+	sizeCode := (b[3] >> 1) & 0x07
+	if bsCode == 0 || rateCode == 15 || chans > 10 || sizeCode == 3 || b[3]&1 != 0 {
+		return FrameHeader{}, 0, ErrFrameHeader
+	}
+	h.Channels = chans + 1
+	if chans >= 8 {
+		h.Channels = 2
+	}
+	h.BitsPerSample = sampleSizes[sizeCode]
+
+	n := 4
+	num, l := utf8Number(b[n:])
+	if l == 0 {
+		return FrameHeader{}, 0, ErrFrameHeader
+	}
+	h.Number = num
+	n += l
+
+	switch {
+	case bsCode == 1:
+		h.BlockSize = 192
+	case bsCode <= 5:
+		h.BlockSize = 576 << (bsCode - 2)
+	case bsCode == 6:
+		if len(b) < n+1 {
+			return FrameHeader{}, 0, ErrShort
+		}
+		h.BlockSize = int(b[n]) + 1
+		n++
+	case bsCode == 7:
+		if len(b) < n+2 {
+			return FrameHeader{}, 0, ErrShort
+		}
+		h.BlockSize = int(b[n])<<8 | int(b[n+1]) + 1
+		n += 2
+	default:
+		h.BlockSize = 256 << (bsCode - 8)
+	}
+
+	switch rateCode {
+	case 12:
+		if len(b) < n+1 {
+			return FrameHeader{}, 0, ErrShort
+		}
+		h.SampleRate = uint32(b[n]) * 1000
+		n++
+	case 13, 14:
+		if len(b) < n+2 {
+			return FrameHeader{}, 0, ErrShort
+		}
+		h.SampleRate = uint32(b[n])<<8 | uint32(b[n+1])
+		if rateCode == 14 {
+			h.SampleRate *= 10
+		}
+		n += 2
+	default:
+		h.SampleRate = sampleRates[rateCode]
+	}
+
+	if len(b) < n+1 {
+		return FrameHeader{}, 0, ErrShort
+	}
+	if crc8(b[:n]) != b[n] {
+		return FrameHeader{}, 0, ErrFrameCRC
+	}
+	return h, n + 1, nil
+}
+
+// utf8Number decodes the UTF-8-like coded number at the beginning of b,
+// returning its length, or 0 if it's invalid.
+func utf8Number(b []byte) (uint64, int) {
+	if len(b) == 0 {
+		return 0, 0
+	}
+	c := b[0]
+	var l int
+	switch {
+	case c&0x80 == 0:
+		return uint64(c), 1
+	case c&0xe0 == 0xc0:
+		l = 2
+	case c&0xf0 == 0xe0:
+		l = 3
+	case c&0xf8 == 0xf0:
+		l = 4
+	case c&0xfc == 0xf8:
+		l = 5
+	case c&0xfe == 0xfc:
+		l = 6
+	case c == 0xfe:
+		l = 7
+	default:
+		return 0, 0
+	}
+	if len(b) < l {
+		return 0, 0
+	}
+	v := uint64(c & (0x7f >> l))
+	for _, c := range b[1:l] {
+		if c&0xc0 != 0x80 {
+			return 0, 0
+		}
+		v = v<<6 | uint64(c&0x3f)
+	}
+	return v, l
+}
+
+var (
+	crc8Table  [256]uint8
+	crc16Table [256]uint16
+)
+
+func init() {
+	for i := range crc8Table {
+		c := uint8(i)
+		for j := 0; j < 8; j++ {
+			if c&0x80 != 0 {
+				c = c<<1 ^ 0x07
+			} else {
+				c <<= 1
+			}
+		}
+		crc8Table[i] = c
+	}
+	for i := range crc16Table {
+		c := uint16(i) << 8
+		for j := 0; j < 8; j++ {
+			if c&0x8000 != 0 {
+				c = c<<1 ^ 0x8005
+			} else {
+				c <<= 1
+			}
+		}
+		crc16Table[i] = c
+	}
+}
+
+// crc8 computes the CRC-8 of a frame header.
+func crc8(b []byte) uint8 {
+	var c uint8
+	for _, x := range b {
+		c = crc8Table[c^x]
+	}
+	return c
+}
+
+// crc16 updates the CRC-16 of a frame with b.
+func crc16(c uint16, b []byte) uint16 {
+	for _, x := range b {
+		c = c<<8 ^ crc16Table[byte(c>>8)^x]
+	}
+	return c
+}
@@ -0,0 +1,201 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package flac
+
+import (
+	"bytes"
+	"io"
+
+	"mccoy.space/g/ogg"
+)
+
+// ToOgg converts the native FLAC file in src to an Ogg FLAC stream with the given serial in dst.
+// Frames are found by their sync codes and checked with their CRCs, but not decoded.
+func ToOgg(dst io.Writer, src io.Reader, serial uint32) error {
+	var magic [4]byte
+	_, err := io.ReadFull(src, magic[:])
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrMagic
+	}
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(magic[:], nativeMagic) {
+		return ErrMagic
+	}
+
+	var si StreamInfo
+	var metadata []MetadataBlock
+	for i, last := 0, false; !last; i++ {
+		var hdr [4]byte
+		_, err = io.ReadFull(src, hdr[:])
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return err
+		}
+		var t BlockType
+		var n int
+		t, n, last, err = parseBlockHeader(hdr[:])
+		if err != nil {
+			return err
+		}
+		data := make([]byte, n)
+		_, err = io.ReadFull(src, data)
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return err
+		}
+
+		if (i == 0) != (t == StreamInfoBlock) {
+			return ErrStreamInfo
+		}
+		if i == 0 {
+			err = si.UnmarshalBinary(data)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		metadata = append(metadata, MetadataBlock{t, data})
+	}
+
+	w, err := NewWriter(dst, serial, &si, metadata, ogg.PagePolicy{})
+	if err != nil {
+		return err
+	}
+	// Each frame is written once the next is found, so the last can end the stream.
+	s := frameScanner{r: src}
+	var prev []byte
+	for {
+		frame, err := s.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if prev != nil {
+			err = w.WriteFrame(prev)
+			if err != nil {
+				return err
+			}
+		}
+		prev = append(prev[:0], frame...)
+	}
+	if prev == nil {
+		return w.Close()
+	}
+	return w.WriteLastFrame(prev)
+}
+
+// ToNative converts the first Ogg FLAC stream in src to a native FLAC file in dst.
+func ToNative(dst io.Writer, src io.Reader) error {
+	r, err := NewReader(src)
+	if err != nil {
+		return err
+	}
+
+	sib, err := r.StreamInfo.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	b := append([]byte(nil), nativeMagic...)
+	b = appendBlock(b, StreamInfoBlock, sib, len(r.Metadata) == 0)
+	for i, m := range r.Metadata {
+		b = appendBlock(b, m.Type, m.Data, i == len(r.Metadata)-1)
+	}
+	_, err = dst.Write(b)
+	if err != nil {
+		return err
+	}
+
+	for {
+		p, err := r.ReadPacket()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		_, err = dst.Write(p.Data)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// A frameScanner splits the frames of a native FLAC file.
+// A frame ends where the bytes so far pass the CRC-16 check
+// and are followed by a valid frame header, or at the end of the input.
+type frameScanner struct {
+	r   io.Reader
+	buf []byte
+	eof bool
+}
+
+// The most bytes needed to parse a frame header.
+const maxFrameHeader = 16
+
+// next returns the next frame, which is valid until the next call.
+func (s *frameScanner) next() ([]byte, error) {
+	err := s.fill(maxFrameHeader)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.buf) == 0 {
+		return nil, io.EOF
+	}
+	_, n, err := ParseFrameHeader(s.buf)
+	if err != nil {
+		return nil, err
+	}
+
+	crc := crc16(0, s.buf[:n])
+	for i := n; ; i++ {
+		err = s.fill(i + maxFrameHeader)
+		if err != nil {
+			return nil, err
+		}
+		if i == len(s.buf) {
+			if crc != 0 {
+				return nil, ErrFrameCRC
+			}
+			frame := s.buf
+			s.buf = s.buf[len(s.buf):]
+			return frame, nil
+		}
+
+		if crc == 0 && s.buf[i] == 0xff {
+			if _, _, err := ParseFrameHeader(s.buf[i:]); err == nil {
+				frame := s.buf[:i]
+				s.buf = s.buf[i:]
+				return frame, nil
+			}
+		}
+		crc = crc16(crc, s.buf[i:i+1])
+	}
+}
+
+// fill reads until the buffer has at least n bytes, or the input ends.
+func (s *frameScanner) fill(n int) error {
+	for len(s.buf) < n && !s.eof {
+		if cap(s.buf)-len(s.buf) < 4096 {
+			// Grow, moving the unread bytes to the front.
+			nb := make([]byte, len(s.buf), 2*cap(s.buf)+4096)
+			copy(nb, s.buf)
+			s.buf = nb
+		}
+		m, err := s.r.Read(s.buf[len(s.buf):cap(s.buf)])
+		s.buf = s.buf[:len(s.buf)+m]
+		if err == io.EOF {
+			s.eof = true
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
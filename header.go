@@ -40,6 +40,32 @@ type pageHeader struct {
 	Nsegs         byte    // 26
 }
 
+// parse sets h from the first headsz bytes of b.
+func (h *pageHeader) parse(b []byte) {
+	_ = b[headsz-1]
+	copy(h.OggS[:], b[0:4])
+	h.StreamVersion = b[4]
+	h.HeaderType = b[5]
+	h.Granule = int64(byteOrder.Uint64(b[6:14]))
+	h.Serial = byteOrder.Uint32(b[14:18])
+	h.Page = byteOrder.Uint32(b[18:22])
+	h.Crc = byteOrder.Uint32(b[22:26])
+	h.Nsegs = b[26]
+}
+
+// put writes h to the first headsz bytes of b.
+func (h *pageHeader) put(b []byte) {
+	_ = b[headsz-1]
+	copy(b[0:4], h.OggS[:])
+	b[4] = h.StreamVersion
+	b[5] = h.HeaderType
+	byteOrder.PutUint64(b[6:14], uint64(h.Granule))
+	byteOrder.PutUint32(b[14:18], h.Serial)
+	byteOrder.PutUint32(b[18:22], h.Page)
+	byteOrder.PutUint32(b[22:26], h.Crc)
+	b[26] = h.Nsegs
+}
+
 const (
 	// Continuation of packet
 	COP byte = 1 << iota
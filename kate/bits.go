@@ -0,0 +1,205 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package kate
+
+import (
+	"image/color"
+	"math"
+	"math/bits"
+)
+
+// A bitReader reads the bit-packed fields of Kate headers, least significant bit first.
+// After reading past the end of b, short is true, and reads return zero.
+type bitReader struct {
+	b     []byte
+	n     int
+	short bool
+}
+
+func (r *bitReader) read(bits int) uint32 {
+	if r.n+bits > len(r.b)*8 {
+		r.short = true
+		r.n = len(r.b) * 8
+		return 0
+	}
+	var v uint32
+	for i := 0; i < bits; i++ {
+		v |= uint32(r.b[r.n>>3]>>(r.n&7)&1) << i
+		r.n++
+	}
+	return v
+}
+
+func (r *bitReader) skip(bits int) {
+	if bits < 0 || r.n+bits > len(r.b)*8 {
+		r.short = true
+		r.n = len(r.b) * 8
+		return
+	}
+	r.n += bits
+}
+
+// read32v reads a variable-length integer.
+// Values from 0 to 14 take 4 bits, and others are 15 followed by
+// a sign bit, the number of bits in the magnitude less one, and the magnitude.
+func (r *bitReader) read32v() int32 {
+	v := r.read(4)
+	if v < 15 {
+		return int32(v)
+	}
+	neg := r.read(1) == 1
+	v = r.read(int(r.read(5)) + 1)
+	if neg {
+		return -int32(v)
+	}
+	return int32(v)
+}
+
+// skipWarps skips extension blocks, each a size in bits and that many bits,
+// through the empty one that ends them.
+func (r *bitReader) skipWarps() {
+	for !r.short {
+		size := r.read32v()
+		if size == 0 {
+			return
+		}
+		r.skip(int(size))
+	}
+}
+
+// readFloats reads n 16.16 fixed-point numbers, packed by trimming the bits
+// that are zero in all of them: a 4-bit count of high bits and one of low bits,
+// then, for each, a sign bit if high bits are trimmed and the remaining bits of its magnitude.
+func (r *bitReader) readFloats(n int) []float32 {
+	head, tail := int(r.read(4)), int(r.read(4))
+	f := make([]float32, n)
+	for i := range f {
+		neg := head > 0 && r.read(1) == 1
+		v := int32(r.read(32-head-tail) << tail)
+		if neg {
+			v = -v
+		}
+		f[i] = float32(v) / 65536
+	}
+	return f
+}
+
+func (r *bitReader) readColor() color.NRGBA {
+	return color.NRGBA{R: uint8(r.read(8)), G: uint8(r.read(8)), B: uint8(r.read(8)), A: uint8(r.read(8))}
+}
+
+// readString reads a length, as a variable-length integer, and that many bytes.
+func (r *bitReader) readString() string {
+	n := r.read32v()
+	if n < 0 || int(n) > len(r.b)-r.n/8 {
+		r.skip(-1)
+		return ""
+	}
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(r.read(8))
+	}
+	return string(b)
+}
+
+// A bitWriter appends bit-packed fields to b, least significant bit first.
+type bitWriter struct {
+	b []byte
+	n int
+}
+
+func (w *bitWriter) write(v uint32, bits int) {
+	for i := 0; i < bits; i++ {
+		if w.n&7 == 0 {
+			w.b = append(w.b, 0)
+		}
+		w.b[len(w.b)-1] |= byte(v>>i&1) << (w.n & 7)
+		w.n++
+	}
+}
+
+func (w *bitWriter) write32v(v int32) {
+	if v >= 0 && v < 15 {
+		w.write(uint32(v), 4)
+		return
+	}
+	w.write(15, 4)
+	m := uint32(v)
+	if v < 0 {
+		w.write(1, 1)
+		m = uint32(-v)
+	} else {
+		w.write(0, 1)
+	}
+	bits := 1
+	for m>>bits != 0 {
+		bits++
+	}
+	w.write(uint32(bits-1), 5)
+	w.write(m, bits)
+}
+
+func (w *bitWriter) writeBool(v bool) {
+	if v {
+		w.write(1, 1)
+	} else {
+		w.write(0, 1)
+	}
+}
+
+// writeFloats writes numbers as readFloats reads them, rounded to 16.16 fixed point.
+func (w *bitWriter) writeFloats(f []float32) {
+	v := make([]int32, len(f))
+	var m, t uint32
+	for i, x := range f {
+		v[i] = int32(math.Round(float64(x) * 65536))
+		a := v[i]
+		if a < 0 {
+			a = -a
+		}
+		m |= uint32(a)
+		t |= uint32(v[i])
+	}
+	head, tail := bits.LeadingZeros32(m), bits.TrailingZeros32(t)
+	if head > 15 {
+		head = 15
+	}
+	if tail > 15 {
+		tail = 15
+	}
+	w.write(uint32(head), 4)
+	w.write(uint32(tail), 4)
+	for _, x := range v {
+		if head > 0 {
+			w.writeBool(x < 0)
+			if x < 0 {
+				x = -x
+			}
+		}
+		w.write(uint32(x)>>tail, 32-head-tail)
+	}
+}
+
+func (w *bitWriter) writeColor(c color.NRGBA) {
+	w.write(uint32(c.R), 8)
+	w.write(uint32(c.G), 8)
+	w.write(uint32(c.B), 8)
+	w.write(uint32(c.A), 8)
+}
+
+func (w *bitWriter) writeString(s string) {
+	w.write32v(int32(len(s)))
+	for i := 0; i < len(s); i++ {
+		w.write(uint32(s[i]), 8)
+	}
+}
+
+// writeWarp writes an extension block of the fields f writes, preceded by its size in bits.
+func (w *bitWriter) writeWarp(f func(*bitWriter)) {
+	var warp bitWriter
+	f(&warp)
+	w.write32v(int32(warp.n))
+	for i := 0; i < warp.n; i++ {
+		w.write(uint32(warp.b[i>>3]>>(i&7)&1), 1)
+	}
+}
@@ -0,0 +1,667 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+/*
+Package kate implements the Kate subtitle and karaoke format as defined in
+https://wiki.xiph.org/OggKate and libkate's format documentation,
+and the interpretation of its granule positions.
+
+The identification and comment headers and the fixed fields of text events are fully supported.
+The region and style headers are parsed following libkate's 0.x bitstream layout,
+and the other headers (curves, motions, palettes, bitmaps, and fonts)
+are kept as raw packets, so they can be copied between streams but not inspected.
+Likewise, the optional fields after the text of an event are kept as raw bits.
+*/
+package kate
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image/color"
+	"io"
+	"math/big"
+	"time"
+
+	"mccoy.space/g/ogg"
+	"mccoy.space/g/ogg/comment"
+)
+
+// magic follows the packet type in header packets.
+const magic = "kate\x00\x00\x00"
+
+// ErrMagic is the error used when a header packet lacks its magic signature.
+var ErrMagic = errors.New("missing Kate header signature")
+
+// ErrShort is the error used when a packet is truncated.
+var ErrShort = errors.New("truncated Kate packet")
+
+// ErrVersion is the error used for an identification header with an incompatible version.
+var ErrVersion = errors.New("unsupported Kate version")
+
+// ErrInfo is the error used for an identification header with invalid fields.
+var ErrInfo = errors.New("invalid Kate identification header")
+
+// ErrPacket is the error used for a data packet of the wrong type.
+var ErrPacket = errors.New("unexpected Kate packet type")
+
+// ErrNoStream is the error used by NewReader when there is no Kate stream.
+var ErrNoStream = errors.New("no Kate stream")
+
+// A PacketType is the first byte of a packet.
+// Header packets have the high bit set.
+type PacketType uint8
+
+const (
+	TextPacket      PacketType = 0x00
+	KeepalivePacket PacketType = 0x01
+	RepeatPacket    PacketType = 0x02
+	EndPacket       PacketType = 0x7f
+	InfoPacket      PacketType = 0x80
+	CommentPacket   PacketType = 0x81
+	RegionPacket    PacketType = 0x82
+	StylePacket     PacketType = 0x83
+	CurvePacket     PacketType = 0x84
+	MotionPacket    PacketType = 0x85
+	PalettePacket   PacketType = 0x86
+	BitmapPacket    PacketType = 0x87
+	FontPacket      PacketType = 0x88
+)
+
+// IsHeader reports whether packet is a header packet.
+func IsHeader(packet []byte) bool {
+	return len(packet) > 0 && packet[0]&0x80 != 0
+}
+
+// A Directionality is the direction text flows in.
+type Directionality uint8
+
+const (
+	LeftToRightTopToBottom Directionality = iota
+	RightToLeftTopToBottom
+	TopToBottomRightToLeft
+	TopToBottomLeftToRight
+)
+
+// An Info is the identification header that begins a Kate stream.
+type Info struct {
+	VersionMajor uint8
+	VersionMinor uint8
+	// Headers is the number of header packets, including this one.
+	Headers uint8
+	// TextEncoding is 0, for UTF-8.
+	TextEncoding   uint8
+	Directionality Directionality
+	// GranuleShift is the number of bits in granule positions
+	// for the offset from the start of the earliest active event.
+	GranuleShift uint8
+	// CanvasWidth and CanvasHeight are the size of the video the stream was made for,
+	// or 0 if unspecified.
+	// They are stored as 12 bits shifted by up to 15.
+	CanvasWidth  uint32
+	CanvasHeight uint32
+	// The granule rate is in granules per second.
+	GranuleRateNumerator   uint32
+	GranuleRateDenominator uint32
+	// Language is an RFC 3066 language tag, and Category is like "subtitles" or "lyrics".
+	// Each is at most 15 bytes.
+	Language string
+	Category string
+}
+
+const infoSize = 64
+
+// Validate checks info against the rules of the Kate specification.
+func (info *Info) Validate() error {
+	if info.VersionMajor != 0 {
+		return ErrVersion
+	}
+	_, cw := canvasSize(info.CanvasWidth)
+	_, ch := canvasSize(info.CanvasHeight)
+	if info.Headers < 2 || info.TextEncoding != 0 || info.Directionality > TopToBottomLeftToRight ||
+		info.GranuleShift > 62 || !cw || !ch ||
+		info.GranuleRateNumerator == 0 || info.GranuleRateDenominator == 0 ||
+		len(info.Language) > 15 || len(info.Category) > 15 {
+		return ErrInfo
+	}
+	return nil
+}
+
+// canvasSize encodes a canvas dimension as a 4-bit shift and a 12-bit base,
+// reporting whether it can be represented exactly.
+func canvasSize(v uint32) (uint16, bool) {
+	var shift uint32
+	for v >= 1<<12 && v&1 == 0 && shift < 15 {
+		v >>= 1
+		shift++
+	}
+	return uint16(shift | v<<4), v < 1<<12
+}
+
+// MarshalBinary encodes info as an identification header packet, after validating it.
+func (info *Info) MarshalBinary() ([]byte, error) {
+	err := info.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, infoSize)
+	b[0] = byte(InfoPacket)
+	copy(b[1:], magic)
+	b[9], b[10], b[11] = info.VersionMajor, info.VersionMinor, info.Headers
+	b[12], b[13], b[15] = info.TextEncoding, byte(info.Directionality), info.GranuleShift
+	cw, _ := canvasSize(info.CanvasWidth)
+	ch, _ := canvasSize(info.CanvasHeight)
+	binary.LittleEndian.PutUint16(b[16:], cw)
+	binary.LittleEndian.PutUint16(b[18:], ch)
+	binary.LittleEndian.PutUint32(b[24:], info.GranuleRateNumerator)
+	binary.LittleEndian.PutUint32(b[28:], info.GranuleRateDenominator)
+	copy(b[32:47], info.Language)
+	copy(b[48:63], info.Category)
+	return b, nil
+}
+
+// UnmarshalBinary decodes an identification header packet into info, and validates it.
+func (info *Info) UnmarshalBinary(b []byte) error {
+	if !isHeader(b, InfoPacket) {
+		return ErrMagic
+	}
+	if len(b) < infoSize {
+		return ErrShort
+	}
+
+	cw := uint32(binary.LittleEndian.Uint16(b[16:]))
+	ch := uint32(binary.LittleEndian.Uint16(b[18:]))
+	*info = Info{
+		VersionMajor:           b[9],
+		VersionMinor:           b[10],
+		Headers:                b[11],
+		TextEncoding:           b[12],
+		Directionality:         Directionality(b[13]),
+		GranuleShift:           b[15],
+		CanvasWidth:            cw >> 4 << (cw & 0x0f),
+		CanvasHeight:           ch >> 4 << (ch & 0x0f),
+		GranuleRateNumerator:   binary.LittleEndian.Uint32(b[24:]),
+		GranuleRateDenominator: binary.LittleEndian.Uint32(b[28:]),
+		Language:               cstring(b[32:48]),
+		Category:               cstring(b[48:64]),
+	}
+	return info.Validate()
+}
+
+// isHeader reports whether b is a header packet of the given type.
+func isHeader(b []byte, t PacketType) bool {
+	return len(b) >= 1+len(magic) && b[0] == byte(t) && string(b[1:1+len(magic)]) == magic
+}
+
+func cstring(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// SplitGranule splits a granule position into the start time of the earliest active event
+// and the time since then, both in granules.
+func (info *Info) SplitGranule(granule int64) (base, offset int64) {
+	return granule >> info.GranuleShift, granule & (1<<info.GranuleShift - 1)
+}
+
+// Granule joins a base time and an offset from it into a granule position.
+func (info *Info) Granule(base, offset int64) int64 {
+	return base<<info.GranuleShift | offset
+}
+
+// Time returns the time of the given granule position.
+func (info *Info) Time(granule int64) time.Duration {
+	base, off := info.SplitGranule(granule)
+	return info.Duration(base + off)
+}
+
+// Duration converts a number of granules, like an event's start time, to a duration.
+func (info *Info) Duration(granules int64) time.Duration {
+	return ogg.GranuleDuration(granules, int64(info.GranuleRateNumerator), int64(info.GranuleRateDenominator))
+}
+
+// Granules converts a non-negative duration to the nearest number of granules.
+func (info *Info) Granules(d time.Duration) int64 {
+	n := new(big.Int).Mul(big.NewInt(int64(d)), big.NewInt(int64(info.GranuleRateNumerator)))
+	den := new(big.Int).Mul(big.NewInt(int64(info.GranuleRateDenominator)), big.NewInt(int64(time.Second)))
+	n.Add(n, new(big.Int).Rsh(den, 1))
+	return n.Quo(n, den).Int64()
+}
+
+// A Comment is the comment header that follows the identification header.
+type Comment struct {
+	comment.Header
+}
+
+// MarshalBinary encodes c as a comment header packet.
+func (c *Comment) MarshalBinary() ([]byte, error) {
+	b := append([]byte{byte(CommentPacket)}, magic...)
+	return c.Header.Append(b), nil
+}
+
+// UnmarshalBinary decodes a comment header packet into c.
+func (c *Comment) UnmarshalBinary(b []byte) error {
+	if !isHeader(b, CommentPacket) {
+		return ErrMagic
+	}
+	h, _, err := comment.Parse(b[1+len(magic):])
+	if err != nil {
+		return err
+	}
+	c.Header = h
+	return nil
+}
+
+// A Metric is the unit of a region's position and size, or of a style's margins and font size.
+type Metric uint8
+
+const (
+	// Percent is hundredths of the canvas size.
+	Percent Metric = iota
+	// Millionths is millionths of the canvas size.
+	Millionths
+	// Pixels is pixels on the canvas.
+	Pixels
+)
+
+// A Region is a rectangle of the canvas where text is displayed.
+type Region struct {
+	Metric Metric
+	X      int32
+	Y      int32
+	Width  int32
+	Height int32
+	// Style is the index of the region's default style, or -1 for none.
+	Style int32
+	// Clip is whether text is clipped to the region.
+	Clip bool
+}
+
+// ParseRegions decodes the regions in a region header packet.
+func ParseRegions(b []byte) ([]Region, error) {
+	if !isHeader(b, RegionPacket) {
+		return nil, ErrMagic
+	}
+	r := bitReader{b: b[1+len(magic):]}
+	n := r.read32v()
+	if r.short || n < 0 {
+		return nil, ErrShort
+	}
+
+	var regions []Region
+	for i := int32(0); i < n && !r.short; i++ {
+		g := Region{
+			Metric: Metric(r.read(8)),
+			X:      r.read32v(),
+			Y:      r.read32v(),
+			Width:  r.read32v(),
+			Height: r.read32v(),
+			Style:  r.read32v(),
+		}
+		// The clip flag came later, in the first extension block.
+		if size := r.read32v(); size > 0 {
+			g.Clip = r.read(1) == 1
+			r.skip(int(size) - 1)
+			r.skipWarps()
+		}
+		regions = append(regions, g)
+	}
+	r.skipWarps()
+	if r.short {
+		return nil, ErrShort
+	}
+	return regions, nil
+}
+
+// MarshalRegions encodes regions as a region header packet.
+func MarshalRegions(regions []Region) []byte {
+	w := bitWriter{b: append([]byte{byte(RegionPacket)}, magic...)}
+	w.write32v(int32(len(regions)))
+	for _, g := range regions {
+		w.write(uint32(g.Metric), 8)
+		w.write32v(g.X)
+		w.write32v(g.Y)
+		w.write32v(g.Width)
+		w.write32v(g.Height)
+		w.write32v(g.Style)
+		w.write32v(1)
+		if g.Clip {
+			w.write(1, 1)
+		} else {
+			w.write(0, 1)
+		}
+		w.write32v(0)
+	}
+	w.write32v(0)
+	return w.b
+}
+
+// A WrapMode is how text that's too long for its region is broken into lines.
+type WrapMode int32
+
+const (
+	WrapWord WrapMode = iota
+	WrapNone
+)
+
+// A Style is how text is drawn: its alignment, margins, font, and colors.
+type Style struct {
+	// HAlign and VAlign place text in its region, from -1 (left or top) to 1 (right or bottom).
+	HAlign float32
+	VAlign float32
+	// The margins are in MarginMetric units, and the font size is in FontMetric units.
+	LeftMargin   float32
+	TopMargin    float32
+	RightMargin  float32
+	BottomMargin float32
+	FontWidth    float32
+	FontHeight   float32
+	// The colors are not premultiplied by alpha.
+	TextColor       color.NRGBA
+	BackgroundColor color.NRGBA
+	DrawColor       color.NRGBA
+	FontMetric      Metric
+	MarginMetric    Metric
+	Bold            bool
+	Italics         bool
+	Underline       bool
+	Strike          bool
+	// Justify and Font came later, in the first extension block,
+	// and WrapMode in the second.
+	Justify  bool
+	Font     string
+	WrapMode WrapMode
+}
+
+// ParseStyles decodes the styles in a style header packet.
+func ParseStyles(b []byte) ([]Style, error) {
+	if !isHeader(b, StylePacket) {
+		return nil, ErrMagic
+	}
+	r := bitReader{b: b[1+len(magic):]}
+	n := r.read32v()
+	if r.short || n < 0 {
+		return nil, ErrShort
+	}
+
+	var styles []Style
+	for i := int32(0); i < n && !r.short; i++ {
+		f := r.readFloats(8)
+		s := Style{
+			HAlign:          f[0],
+			VAlign:          f[1],
+			LeftMargin:      f[2],
+			TopMargin:       f[3],
+			RightMargin:     f[4],
+			BottomMargin:    f[5],
+			FontWidth:       f[6],
+			FontHeight:      f[7],
+			TextColor:       r.readColor(),
+			BackgroundColor: r.readColor(),
+			DrawColor:       r.readColor(),
+			FontMetric:      Metric(r.read(8)),
+			MarginMetric:    Metric(r.read(8)),
+			Bold:            r.read(1) == 1,
+			Italics:         r.read(1) == 1,
+			Underline:       r.read(1) == 1,
+			Strike:          r.read(1) == 1,
+		}
+		if size := r.read32v(); size > 0 {
+			start := r.n
+			s.Justify = r.read(1) == 1
+			s.Font = r.readString()
+			r.skip(int(size) - (r.n - start))
+			if size := r.read32v(); size > 0 {
+				start := r.n
+				s.WrapMode = WrapMode(r.read32v())
+				r.skip(int(size) - (r.n - start))
+				r.skipWarps()
+			}
+		}
+		styles = append(styles, s)
+	}
+	r.skipWarps()
+	if r.short {
+		return nil, ErrShort
+	}
+	return styles, nil
+}
+
+// MarshalStyles encodes styles as a style header packet.
+func MarshalStyles(styles []Style) []byte {
+	w := bitWriter{b: append([]byte{byte(StylePacket)}, magic...)}
+	w.write32v(int32(len(styles)))
+	for _, s := range styles {
+		w.writeFloats([]float32{
+			s.HAlign, s.VAlign,
+			s.LeftMargin, s.TopMargin, s.RightMargin, s.BottomMargin,
+			s.FontWidth, s.FontHeight,
+		})
+		w.writeColor(s.TextColor)
+		w.writeColor(s.BackgroundColor)
+		w.writeColor(s.DrawColor)
+		w.write(uint32(s.FontMetric), 8)
+		w.write(uint32(s.MarginMetric), 8)
+		w.writeBool(s.Bold)
+		w.writeBool(s.Italics)
+		w.writeBool(s.Underline)
+		w.writeBool(s.Strike)
+		w.writeWarp(func(w *bitWriter) {
+			w.writeBool(s.Justify)
+			w.writeString(s.Font)
+		})
+		w.writeWarp(func(w *bitWriter) {
+			w.write32v(int32(s.WrapMode))
+		})
+		w.write32v(0)
+	}
+	w.write32v(0)
+	return w.b
+}
+
+// An Event is a text packet, which displays text for a time.
+type Event struct {
+	// Repeat is whether the packet is a repeat of an earlier event,
+	// for players that start in the middle of the stream.
+	Repeat bool
+	// Start and Duration are in granules.
+	Start    int64
+	Duration int64
+	// Backlink is the time in granules from the start of the earliest event
+	// still active at Start.
+	Backlink int64
+	Text     string
+	// Extra is the optional fields after the text, which aren't parsed.
+	// If it's empty, a zero byte is written in its place, leaving the fields unset.
+	Extra []byte
+}
+
+// MarshalBinary encodes e as a text or repeat packet.
+func (e *Event) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 1, 29+len(e.Text)+len(e.Extra)+1)
+	if e.Repeat {
+		b[0] = byte(RepeatPacket)
+	}
+	b = binary.LittleEndian.AppendUint64(b, uint64(e.Start))
+	b = binary.LittleEndian.AppendUint64(b, uint64(e.Duration))
+	b = binary.LittleEndian.AppendUint64(b, uint64(e.Backlink))
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(e.Text)))
+	b = append(b, e.Text...)
+	if len(e.Extra) == 0 {
+		return append(b, 0), nil
+	}
+	return append(b, e.Extra...), nil
+}
+
+// UnmarshalBinary decodes a text or repeat packet into e.
+func (e *Event) UnmarshalBinary(b []byte) error {
+	if len(b) == 0 || PacketType(b[0]) != TextPacket && PacketType(b[0]) != RepeatPacket {
+		return ErrPacket
+	}
+	if len(b) < 29 {
+		return ErrShort
+	}
+	n := binary.LittleEndian.Uint32(b[25:])
+	if uint32(len(b)-29) < n {
+		return ErrShort
+	}
+	*e = Event{
+		Repeat:   PacketType(b[0]) == RepeatPacket,
+		Start:    int64(binary.LittleEndian.Uint64(b[1:])),
+		Duration: int64(binary.LittleEndian.Uint64(b[9:])),
+		Backlink: int64(binary.LittleEndian.Uint64(b[17:])),
+		Text:     string(b[29 : 29+n]),
+		Extra:    append([]byte(nil), b[29+n:]...),
+	}
+	return nil
+}
+
+// A Reader reads the packets of an Ogg Kate stream.
+type Reader struct {
+	// Serial is the Kate stream's bitstream serial number.
+	Serial  uint32
+	Info    Info
+	Comment Comment
+	// Regions and Styles are from the region and style headers, if there are any.
+	Regions []Region
+	Styles  []Style
+	// Headers are the header packets after the comment header, including the region and style headers.
+	Headers [][]byte
+
+	s *ogg.LogicalStream
+}
+
+// NewReader reads the headers of the first Kate stream in r.
+// Other logical streams in r are ignored.
+func NewReader(r io.Reader) (*Reader, error) {
+	d := ogg.NewDemuxer(r)
+	s, p, err := d.FindStream(func(first []byte) bool {
+		return isHeader(first, InfoPacket)
+	})
+	if err == io.EOF {
+		return nil, ErrNoStream
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	kr := &Reader{Serial: s.Serial, s: s}
+	err = kr.Info.UnmarshalBinary(p.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err = s.ReadPacket()
+	if err != nil {
+		return nil, err
+	}
+	err = kr.Comment.UnmarshalBinary(p.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 2; i < int(kr.Info.Headers); i++ {
+		p, err = s.ReadPacket()
+		if err != nil {
+			return nil, err
+		}
+		if !IsHeader(p.Data) {
+			return nil, ErrMagic
+		}
+		if isHeader(p.Data, RegionPacket) {
+			kr.Regions, err = ParseRegions(p.Data)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if isHeader(p.Data, StylePacket) {
+			kr.Styles, err = ParseStyles(p.Data)
+			if err != nil {
+				return nil, err
+			}
+		}
+		kr.Headers = append(kr.Headers, p.Data)
+	}
+	return kr, nil
+}
+
+// ReadPacket returns the next data packet.
+// The error is io.EOF after the last packet.
+func (r *Reader) ReadPacket() (ogg.Packet, error) {
+	return r.s.ReadPacket()
+}
+
+// ReadEvent returns the next text event, skipping other packets, including repeats.
+// The error is io.EOF after the last packet.
+func (r *Reader) ReadEvent() (Event, error) {
+	for {
+		p, err := r.s.ReadPacket()
+		if err != nil {
+			return Event{}, err
+		}
+		if len(p.Data) > 0 && PacketType(p.Data[0]) == TextPacket {
+			var e Event
+			err = e.UnmarshalBinary(p.Data)
+			return e, err
+		}
+	}
+}
+
+// A Writer writes an Ogg Kate stream.
+type Writer struct {
+	info Info
+	pw   *ogg.PacketWriter
+}
+
+// NewWriter creates a Writer of an Ogg Kate stream with the given serial,
+// writing the header packets immediately.
+// The headers after the comment header must number info.Headers-2.
+func NewWriter(w io.Writer, serial uint32, info *Info, c *Comment, headers [][]byte, policy ogg.PagePolicy) (*Writer, error) {
+	ib, err := info.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	if len(headers) != int(info.Headers)-2 {
+		return nil, ErrInfo
+	}
+	for _, h := range headers {
+		if !IsHeader(h) {
+			return nil, ErrMagic
+		}
+	}
+	cb, err := c.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	pw := ogg.NewPacketWriter(serial, w, policy)
+	for _, h := range append([][]byte{ib, cb}, headers...) {
+		err = pw.WritePacket(0, h)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = pw.Flush()
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{*info, pw}, nil
+}
+
+// WriteEvent writes a text event, whose granule position comes from its Start and Backlink.
+// Events must be written in order of their start times.
+func (w *Writer) WriteEvent(e *Event) error {
+	b, err := e.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return w.pw.WritePacket(w.info.Granule(e.Start-e.Backlink, e.Backlink), b)
+}
+
+// Close writes an end packet at the given time in granules, and ends the stream.
+// It does not close the underlying Writer.
+func (w *Writer) Close(end int64) error {
+	return w.pw.WriteLastPacket(w.info.Granule(end, 0), []byte{byte(EndPacket)})
+}
@@ -0,0 +1,265 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package kate
+
+import (
+	"bytes"
+	"image/color"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"mccoy.space/g/ogg"
+	"mccoy.space/g/ogg/comment"
+)
+
+var testInfo = Info{
+	VersionMinor:           6,
+	Headers:                4,
+	GranuleShift:           32,
+	CanvasWidth:            1920,
+	CanvasHeight:           1080,
+	GranuleRateNumerator:   1000,
+	GranuleRateDenominator: 1,
+	Language:               "en",
+	Category:               "subtitles",
+}
+
+func TestInfoRoundTrip(t *testing.T) {
+	b, err := testInfo.MarshalBinary()
+	if err != nil {
+		t.Fatal("unexpected MarshalBinary error:", err)
+	}
+	if len(b) != 64 {
+		t.Fatal("expected 64 bytes, got", len(b))
+	}
+
+	var info Info
+	err = info.UnmarshalBinary(b)
+	if err != nil {
+		t.Fatal("unexpected UnmarshalBinary error:", err)
+	}
+	if info != testInfo {
+		t.Fatalf("expected %+v, got %+v", testInfo, info)
+	}
+
+	// The package's codec identification agrees.
+	c, err := ogg.IdentifyCodec(b)
+	if err != nil {
+		t.Fatal("unexpected IdentifyCodec error:", err)
+	}
+	g := info.Granule(1500, 250)
+	if c.Name != "kate" || c.Headers != 4 || c.Time(g) != info.Time(g) {
+		t.Fatalf("codec is wrong: %+v", c)
+	}
+
+	info.CanvasWidth = 4097
+	_, err = info.MarshalBinary()
+	if err != ErrInfo {
+		t.Fatal("expected ErrInfo, got", err)
+	}
+	info.CanvasWidth = 4096 * 3
+	_, err = info.MarshalBinary()
+	if err != nil {
+		t.Fatal("unexpected MarshalBinary error:", err)
+	}
+
+	_, err = (&Comment{}).MarshalBinary()
+	if err != nil {
+		t.Fatal("unexpected MarshalBinary error:", err)
+	}
+	err = info.UnmarshalBinary(b[:63])
+	if err != ErrShort {
+		t.Fatal("expected ErrShort, got", err)
+	}
+}
+
+func TestGranules(t *testing.T) {
+	info := testInfo
+	info.GranuleRateNumerator, info.GranuleRateDenominator = 30000, 1001
+
+	base, off := info.SplitGranule(info.Granule(300, 30))
+	if base != 300 || off != 30 {
+		t.Fatalf("expected 300 and 30, got %d and %d", base, off)
+	}
+	if d := info.Time(info.Granule(0, 30)); d != 1001*time.Millisecond {
+		t.Fatal("expected 1.001s, got", d)
+	}
+	for _, n := range []int64{0, 1, 29, 30, 12345, 1 << 30} {
+		if g := info.Granules(info.Duration(n)); g != n {
+			t.Fatalf("%d granules became %d", n, g)
+		}
+	}
+}
+
+func TestRegions(t *testing.T) {
+	regions := []Region{
+		{Metric: Percent, X: 10, Y: 80, Width: 80, Height: 10, Style: -1},
+		{Metric: Pixels, X: 0, Y: 1000, Width: 1920, Height: 80, Style: 2, Clip: true},
+	}
+	b := MarshalRegions(regions)
+	got, err := ParseRegions(b)
+	if err != nil {
+		t.Fatal("unexpected ParseRegions error:", err)
+	}
+	if !reflect.DeepEqual(got, regions) {
+		t.Fatalf("expected %+v, got %+v", regions, got)
+	}
+
+	_, err = ParseRegions(b[:len(b)-4])
+	if err != ErrShort {
+		t.Fatal("expected ErrShort, got", err)
+	}
+	got, err = ParseRegions(MarshalRegions(nil))
+	if err != nil || got != nil {
+		t.Fatalf("expected no regions, got %v, %v", got, err)
+	}
+}
+
+func TestStyles(t *testing.T) {
+	styles := []Style{
+		{
+			HAlign: -1, VAlign: 0.5,
+			LeftMargin: 2.5, TopMargin: 0, RightMargin: 2.5, BottomMargin: 10,
+			FontWidth: 32, FontHeight: 32,
+			TextColor:       color.NRGBA{255, 255, 255, 255},
+			BackgroundColor: color.NRGBA{0, 0, 0, 128},
+			FontMetric:      Pixels,
+			MarginMetric:    Percent,
+			Italics:         true,
+			Justify:         true,
+			Font:            "DejaVu Sans",
+			WrapMode:        WrapNone,
+		},
+		{Bold: true, Strike: true, FontHeight: 0.125, FontMetric: Millionths},
+	}
+	b := MarshalStyles(styles)
+	got, err := ParseStyles(b)
+	if err != nil {
+		t.Fatal("unexpected ParseStyles error:", err)
+	}
+	if !reflect.DeepEqual(got, styles) {
+		t.Fatalf("expected %+v, got %+v", styles, got)
+	}
+
+	_, err = ParseStyles(b[:len(b)-4])
+	if err != ErrShort {
+		t.Fatal("expected ErrShort, got", err)
+	}
+	_, err = ParseStyles(MarshalRegions(nil))
+	if err != ErrMagic {
+		t.Fatal("expected ErrMagic, got", err)
+	}
+	got, err = ParseStyles(MarshalStyles(nil))
+	if err != nil || got != nil {
+		t.Fatalf("expected no styles, got %v, %v", got, err)
+	}
+}
+
+func TestEvent(t *testing.T) {
+	e := Event{Start: 1500, Duration: 2000, Backlink: 500, Text: "Hello", Extra: []byte{0}}
+	b, err := e.MarshalBinary()
+	if err != nil {
+		t.Fatal("unexpected MarshalBinary error:", err)
+	}
+	var got Event
+	err = got.UnmarshalBinary(b)
+	if err != nil {
+		t.Fatal("unexpected UnmarshalBinary error:", err)
+	}
+	if !reflect.DeepEqual(got, e) {
+		t.Fatalf("expected %+v, got %+v", e, got)
+	}
+
+	err = got.UnmarshalBinary(b[:32])
+	if err != ErrShort {
+		t.Fatal("expected ErrShort, got", err)
+	}
+	err = got.UnmarshalBinary([]byte{byte(KeepalivePacket)})
+	if err != ErrPacket {
+		t.Fatal("expected ErrPacket, got", err)
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	c := Comment{Header: comment.Header{Vendor: "test", Comments: []string{"LANGUAGE=en"}}}
+	regions := []Region{{Metric: Percent, X: 10, Y: 80, Width: 80, Height: 10, Style: -1}}
+	styles := []Style{{HAlign: 0, VAlign: 1, FontHeight: 5, FontMetric: Percent, TextColor: color.NRGBA{255, 255, 0, 255}}}
+	events := []Event{
+		{Start: 1000, Duration: 2000, Text: "One"},
+		{Start: 1500, Duration: 500, Backlink: 500, Text: "Two"},
+		{Start: 4000, Duration: 1000, Text: "Three"},
+	}
+
+	var b bytes.Buffer
+	w, err := NewWriter(&b, 9, &testInfo, &c, [][]byte{MarshalRegions(regions), MarshalStyles(styles)}, ogg.PagePolicy{MaxPackets: 1})
+	if err != nil {
+		t.Fatal("unexpected NewWriter error:", err)
+	}
+	for i := range events {
+		err = w.WriteEvent(&events[i])
+		if err != nil {
+			t.Fatal("unexpected WriteEvent error:", err)
+		}
+	}
+	err = w.Close(5000)
+	if err != nil {
+		t.Fatal("unexpected Close error:", err)
+	}
+
+	r, err := NewReader(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatal("unexpected NewReader error:", err)
+	}
+	if r.Serial != 9 || r.Info != testInfo || !reflect.DeepEqual(r.Comment, c) {
+		t.Fatalf("headers are wrong: %d %+v %+v", r.Serial, r.Info, r.Comment)
+	}
+	if !reflect.DeepEqual(r.Regions, regions) || !reflect.DeepEqual(r.Styles, styles) || len(r.Headers) != 2 {
+		t.Fatalf("regions, styles, or headers are wrong: %+v %+v %q", r.Regions, r.Styles, r.Headers)
+	}
+
+	for i, expect := range events {
+		e, err := r.ReadEvent()
+		if err != nil {
+			t.Fatal("unexpected ReadEvent error:", err)
+		}
+		expect.Extra = []byte{0}
+		if !reflect.DeepEqual(e, expect) {
+			t.Fatalf("event %d: expected %+v, got %+v", i, expect, e)
+		}
+	}
+	_, err = r.ReadEvent()
+	if err != io.EOF {
+		t.Fatal("expected io.EOF, got", err)
+	}
+
+	// The second event's page has a granule position of its start time,
+	// split at the start of the first event.
+	// The end packet is the last, on the EOS page.
+	var granules []int64
+	var last ogg.Page
+	d := ogg.NewDecoder(bytes.NewReader(b.Bytes()))
+	for {
+		p, err := d.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal("unexpected Decode error:", err)
+		}
+		granules = append(granules, p.Granule)
+		last = p
+	}
+	if g := granules[5]; g != testInfo.Granule(1000, 500) || testInfo.Time(g) != 1500*time.Millisecond {
+		t.Fatalf("granule positions are wrong: %x", granules)
+	}
+	if last.Type&ogg.EOS == 0 || len(last.Packets) != 1 || !bytes.Equal(last.Packets[0], []byte{byte(EndPacket)}) {
+		t.Fatalf("expected an EOS page with only the end packet, got %+v", last)
+	}
+
+	_, err = NewWriter(io.Discard, 1, &testInfo, &c, nil, ogg.PagePolicy{})
+	if err != ErrInfo {
+		t.Fatal("expected ErrInfo, got", err)
+	}
+}
@@ -0,0 +1,245 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package ogg
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrLateStream is the error used when adding a stream to a Muxer
+// that has already written data pages.
+var ErrLateStream = errors.New("stream added after data pages")
+
+// ErrDuplicateSerial is the error used when adding a stream to a Muxer
+// with the same serial as another of its streams.
+var ErrDuplicateSerial = errors.New("duplicate stream serial")
+
+// A Muxer groups several logical streams into one physical ogg stream.
+// It writes all of the streams' BOS pages before any other pages,
+// then interleaves the streams' pages in order of time.
+//
+// Pages are held by the Muxer until it knows which comes next,
+// which requires a page from every open stream.
+// A sparse stream, like subtitles, can instead say when it's idle with MuxStream.Idle,
+// so that the other streams' pages aren't held while it has nothing to write.
+type Muxer struct {
+	w       io.Writer
+	policy  PagePolicy
+	streams []*MuxStream
+	data    bool // whether any non-BOS page was written
+}
+
+// A MuxStream is a logical stream being written through a Muxer.
+type MuxStream struct {
+	m      *Muxer
+	pw     *PacketWriter
+	clock  func(granule int64) time.Duration
+	pages  pageQueue
+	last   time.Duration // the time of the last written page with a granule position
+	idle   time.Duration // the time before which the stream has no more pages
+	begun  bool          // whether the BOS page was written
+	closed bool
+}
+
+// pageQueue holds pages written by a PacketWriter,
+// which writes each page with a single call to Write.
+type pageQueue struct {
+	pages [][]byte
+}
+
+func (q *pageQueue) Write(p []byte) (int, error) {
+	q.pages = append(q.pages, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+func (q *pageQueue) pop() []byte {
+	p := q.pages[0]
+	q.pages[0] = nil
+	q.pages = q.pages[1:]
+	return p
+}
+
+// NewMuxer creates a Muxer that writes pages to w,
+// paginating each stream according to policy.
+func NewMuxer(w io.Writer, policy PagePolicy) *Muxer {
+	return &Muxer{w: w, policy: policy}
+}
+
+// AddStream adds a logical stream with the given serial to m.
+// The clock function maps the stream's granule positions to a common timeline,
+// so that pages from different streams can be ordered;
+// if it is nil, granule positions are compared directly.
+//
+// All streams should be added before writing packets to any of them;
+// once data pages have been written, AddStream returns ErrLateStream.
+func (m *Muxer) AddStream(serial uint32, clock func(granule int64) time.Duration) (*MuxStream, error) {
+	if m.data {
+		return nil, ErrLateStream
+	}
+	for _, s := range m.streams {
+		if s.pw.e.serial == serial {
+			return nil, ErrDuplicateSerial
+		}
+	}
+
+	s := &MuxStream{m: m, clock: clock}
+	s.pw = NewPacketWriter(serial, &s.pages, m.policy)
+	m.streams = append(m.streams, s)
+	return s, nil
+}
+
+// WritePacket buffers a packet with the given granule position.
+// The first packet is the stream's BOS page.
+// See PacketWriter.WritePacket.
+func (s *MuxStream) WritePacket(granule int64, packet []byte) error {
+	err := s.pw.WritePacket(granule, packet)
+	if err != nil {
+		return err
+	}
+	return s.m.drain()
+}
+
+// Flush ends the stream's current page, so that it can be interleaved.
+// See PacketWriter.Flush.
+func (s *MuxStream) Flush() error {
+	err := s.pw.Flush()
+	if err != nil {
+		return err
+	}
+	return s.m.drain()
+}
+
+// Idle tells the Muxer that the stream has no more packets before the given time,
+// on the timeline of its clock,
+// so that other streams' pages before then can be written without waiting for it.
+// Packets the stream writes later should be at or after that time.
+// Buffered packets aren't written; Flush them first if they're before that time.
+func (s *MuxStream) Idle(until time.Duration) error {
+	if s.closed {
+		return ErrClosed
+	}
+	if until > s.idle {
+		s.idle = until
+	}
+	return s.m.drain()
+}
+
+// WriteLastPacket writes the stream's last packet, on its EOS page.
+// See PacketWriter.WriteLastPacket.
+func (s *MuxStream) WriteLastPacket(granule int64, packet []byte) error {
+	err := s.pw.WriteLastPacket(granule, packet)
+	if err != nil {
+		return err
+	}
+	s.closed = true
+	return s.m.drain()
+}
+
+// Close writes the stream's EOS page.
+// See PacketWriter.Close.
+func (s *MuxStream) Close() error {
+	err := s.pw.Close()
+	if err != nil {
+		return err
+	}
+	s.closed = true
+	return s.m.drain()
+}
+
+// Close closes any streams that aren't yet closed and writes all remaining pages.
+// It does not close the underlying Writer.
+func (m *Muxer) Close() error {
+	for _, s := range m.streams {
+		if s.closed {
+			continue
+		}
+		err := s.pw.Close()
+		if err != nil {
+			return err
+		}
+		s.closed = true
+	}
+	return m.drain()
+}
+
+// drain writes as many pages as can be ordered.
+func (m *Muxer) drain() error {
+	for _, s := range m.streams {
+		if !s.begun && len(s.pages.pages) > 0 {
+			err := m.write(s)
+			if err != nil {
+				return err
+			}
+			s.begun = true
+		}
+	}
+	for _, s := range m.streams {
+		if !s.begun {
+			return nil
+		}
+	}
+
+	for {
+		var next *MuxStream
+		var nextTime time.Duration
+		for _, s := range m.streams {
+			if len(s.pages.pages) == 0 {
+				continue
+			}
+			t := s.headTime()
+			if next == nil || t < nextTime {
+				next, nextTime = s, t
+			}
+		}
+		if next == nil {
+			return nil
+		}
+
+		// A stream without pages may yet write one before next's.
+		for _, s := range m.streams {
+			if len(s.pages.pages) == 0 && !s.closed && nextTime >= s.idle {
+				return nil
+			}
+		}
+
+		err := m.write(next)
+		if err != nil {
+			return err
+		}
+		m.data = true
+	}
+}
+
+// write writes the first of s's pages.
+func (m *Muxer) write(s *MuxStream) error {
+	p := s.pages.pop()
+	if g := pageGranule(p); g != -1 {
+		s.last = s.timeOf(g)
+	}
+	_, err := m.w.Write(p)
+	return err
+}
+
+// headTime is the time of s's next page.
+// Pages with no granule position are considered to be at the time of the previous page.
+func (s *MuxStream) headTime() time.Duration {
+	g := pageGranule(s.pages.pages[0])
+	if g == -1 {
+		return s.last
+	}
+	return s.timeOf(g)
+}
+
+func (s *MuxStream) timeOf(granule int64) time.Duration {
+	if s.clock == nil {
+		return time.Duration(granule)
+	}
+	return s.clock(granule)
+}
+
+// pageGranule is the granule position of an encoded page.
+func pageGranule(p []byte) int64 {
+	return int64(byteOrder.Uint64(p[6:14]))
+}
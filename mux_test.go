@@ -0,0 +1,171 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package ogg
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestMuxer(t *testing.T) {
+	var b bytes.Buffer
+	m := NewMuxer(&b, PagePolicy{MaxPackets: 1})
+
+	audio, err := m.AddStream(1, func(g int64) time.Duration {
+		return time.Duration(g) * time.Second / 48000
+	})
+	if err != nil {
+		t.Fatal("unexpected AddStream error:", err)
+	}
+	video, err := m.AddStream(2, func(g int64) time.Duration {
+		return time.Duration(g) * time.Second / 25
+	})
+	if err != nil {
+		t.Fatal("unexpected AddStream error:", err)
+	}
+	_, err = m.AddStream(2, nil)
+	if err != ErrDuplicateSerial {
+		t.Fatal("expected ErrDuplicateSerial, got:", err)
+	}
+
+	// The audio is written well ahead of the video,
+	// but each second of audio should follow the second of video before it.
+	err = audio.WritePacket(0, []byte("audio head"))
+	if err != nil {
+		t.Fatal("unexpected WritePacket error:", err)
+	}
+	for i := 1; i <= 2; i++ {
+		err = audio.WritePacket(int64(i*48000), []byte("audio"))
+		if err != nil {
+			t.Fatal("unexpected WritePacket error:", err)
+		}
+	}
+	err = audio.WriteLastPacket(3*48000, []byte("audio"))
+	if err != nil {
+		t.Fatal("unexpected WriteLastPacket error:", err)
+	}
+	if b.Len() == 0 {
+		t.Fatal("expected the audio BOS page to be written")
+	}
+
+	err = video.WritePacket(0, []byte("video head"))
+	if err != nil {
+		t.Fatal("unexpected WritePacket error:", err)
+	}
+	for i := 1; i <= 2; i++ {
+		err = video.WritePacket(int64(i*25)-1, []byte("video"))
+		if err != nil {
+			t.Fatal("unexpected WritePacket error:", err)
+		}
+	}
+	err = video.WriteLastPacket(3*25-1, []byte("video"))
+	if err != nil {
+		t.Fatal("unexpected WriteLastPacket error:", err)
+	}
+
+	_, err = m.AddStream(3, nil)
+	if err != ErrLateStream {
+		t.Fatal("expected ErrLateStream, got:", err)
+	}
+
+	err = m.Close()
+	if err != nil {
+		t.Fatal("unexpected Close error:", err)
+	}
+
+	expect := []struct {
+		serial  uint32
+		typ     byte
+		granule int64
+	}{
+		{1, BOS, 0},
+		{2, BOS, 0},
+		{2, 0, 24},
+		{1, 0, 48000},
+		{2, 0, 49},
+		{1, 0, 96000},
+		{2, EOS, 74},
+		{1, EOS, 144000},
+	}
+
+	pages := decodeAll(t, &b)
+	if len(pages) != len(expect) {
+		t.Fatalf("expected %d pages, got %d", len(expect), len(pages))
+	}
+	for i, x := range expect {
+		p := pages[i]
+		if p.Serial != x.serial || p.Type != x.typ || p.Granule != x.granule {
+			t.Fatalf("page %d is wrong: serial %d, type %d, granule %d", i, p.Serial, p.Type, p.Granule)
+		}
+	}
+}
+
+func TestMuxerIdle(t *testing.T) {
+	var b bytes.Buffer
+	m := NewMuxer(&b, PagePolicy{MaxPackets: 1})
+	seconds := func(g int64) time.Duration { return time.Duration(g) * time.Second }
+
+	audio, err := m.AddStream(1, seconds)
+	if err != nil {
+		t.Fatal("unexpected AddStream error:", err)
+	}
+	subs, err := m.AddStream(2, seconds)
+	if err != nil {
+		t.Fatal("unexpected AddStream error:", err)
+	}
+
+	steps := []struct {
+		s       *MuxStream
+		granule int64
+		idle    bool
+		pages   int
+	}{
+		{audio, 0, false, 1},
+		{subs, 0, false, 2},
+		{audio, 1, false, 2},
+		{audio, 2, false, 2},
+		{audio, 3, false, 2},
+		{audio, 4, false, 2},
+		// audio 1 and 2 can go, and audio 3 is held in case subs has a page at 3
+		{subs, 3, true, 4},
+		// audio 4 is held in case subs has another page at 3
+		{subs, 3, false, 6},
+		{subs, 5, true, 7},
+		// audio 6 is held, since subs is idle only until 5
+		{audio, 6, false, 7},
+	}
+	for i, x := range steps {
+		if x.idle {
+			err = x.s.Idle(seconds(x.granule))
+		} else {
+			err = x.s.WritePacket(x.granule, []byte("packet"))
+		}
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if n := len(decodeAll(t, bytes.NewReader(b.Bytes()))); n != x.pages {
+			t.Fatalf("after step %d, expected %d pages, got %d", i, x.pages, n)
+		}
+	}
+
+	err = m.Close()
+	if err != nil {
+		t.Fatal("unexpected Close error:", err)
+	}
+	expect := []struct {
+		serial  uint32
+		granule int64
+	}{
+		{1, 0}, {2, 0}, {1, 1}, {1, 2}, {1, 3}, {2, 3}, {1, 4}, {2, 3}, {1, 6}, {1, 6},
+	}
+	pages := decodeAll(t, &b)
+	if len(pages) != len(expect) {
+		t.Fatalf("expected %d pages, got %d", len(expect), len(pages))
+	}
+	for i, x := range expect {
+		if pages[i].Serial != x.serial || pages[i].Granule != x.granule {
+			t.Fatalf("page %d is wrong: serial %d, granule %d", i, pages[i].Serial, pages[i].Granule)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+/*
+Package opus implements the Ogg encapsulation of Opus as defined in
+https://www.rfc-editor.org/rfc/rfc7845 :
+the identification header (OpusHead), the comment header (OpusTags),
+and reading and writing Ogg Opus streams.
+*/
+package opus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"mccoy.space/g/ogg"
+	"mccoy.space/g/ogg/comment"
+)
+
+// The sample rate of granule positions in Ogg Opus streams, regardless of the input rate.
+const GranuleRate = 48000
+
+var (
+	headMagic = []byte("OpusHead")
+	tagsMagic = []byte("OpusTags")
+)
+
+// ErrMagic is the error used when a header packet lacks its magic signature.
+var ErrMagic = errors.New("missing Opus header signature")
+
+// ErrShort is the error used when a header packet is truncated.
+var ErrShort = errors.New("truncated Opus header")
+
+// ErrVersion is the error used for an OpusHead with an incompatible version.
+var ErrVersion = errors.New("unsupported OpusHead version")
+
+// ErrChannels is the error used for an OpusHead whose channel count
+// is invalid for its channel mapping family.
+var ErrChannels = errors.New("invalid Opus channel count")
+
+// ErrMapping is the error used for an OpusHead with an invalid channel mapping table.
+var ErrMapping = errors.New("invalid Opus channel mapping table")
+
+// ErrNoStream is the error used by NewReader when there is no Opus stream.
+var ErrNoStream = errors.New("no Opus stream")
+
+// A Head is the identification header, OpusHead, that begins an Ogg Opus stream.
+type Head struct {
+	// Version is the encapsulation version, which is 1 for RFC 7845.
+	// Versions up to 15 are compatible.
+	Version uint8
+	// Channels is the number of output channels.
+	Channels uint8
+	// PreSkip is the number of 48 kHz samples to discard from the beginning of the decoded output.
+	PreSkip uint16
+	// SampleRate is the sample rate of the original input, for information only.
+	SampleRate uint32
+	// OutputGain is the gain to apply to the decoded output, in Q7.8 dB.
+	OutputGain int16
+	// MappingFamily is the channel mapping family.
+	// For family 0, the remaining fields are implied by Channels and are ignored.
+	MappingFamily uint8
+	// StreamCount is the number of Opus streams in each packet.
+	StreamCount uint8
+	// CoupledCount is the number of those streams that are stereo.
+	CoupledCount uint8
+	// Mapping maps each output channel to a decoded channel, or 255 for silence.
+	Mapping []uint8
+}
+
+// Validate checks h against the rules of RFC 7845.
+func (h *Head) Validate() error {
+	if h.Version>>4 != 0 {
+		return ErrVersion
+	}
+	if h.Channels == 0 {
+		return ErrChannels
+	}
+
+	switch h.MappingFamily {
+	case 0:
+		if h.Channels > 2 {
+			return ErrChannels
+		}
+		return nil
+	case 1:
+		if h.Channels > 8 {
+			return ErrChannels
+		}
+	}
+
+	if h.StreamCount == 0 || h.CoupledCount > h.StreamCount || int(h.StreamCount)+int(h.CoupledCount) > 255 {
+		return ErrMapping
+	}
+	if len(h.Mapping) != int(h.Channels) {
+		return ErrMapping
+	}
+	for _, m := range h.Mapping {
+		if m != 255 && int(m) >= int(h.StreamCount)+int(h.CoupledCount) {
+			return ErrMapping
+		}
+	}
+	return nil
+}
+
+// MarshalBinary encodes h as an OpusHead packet, after validating it.
+func (h *Head) MarshalBinary() ([]byte, error) {
+	err := h.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 0, 19+2+len(h.Mapping))
+	b = append(b, headMagic...)
+	b = append(b, h.Version, h.Channels)
+	b = binary.LittleEndian.AppendUint16(b, h.PreSkip)
+	b = binary.LittleEndian.AppendUint32(b, h.SampleRate)
+	b = binary.LittleEndian.AppendUint16(b, uint16(h.OutputGain))
+	b = append(b, h.MappingFamily)
+	if h.MappingFamily != 0 {
+		b = append(b, h.StreamCount, h.CoupledCount)
+		b = append(b, h.Mapping...)
+	}
+	return b, nil
+}
+
+// UnmarshalBinary decodes an OpusHead packet into h, and validates it.
+func (h *Head) UnmarshalBinary(b []byte) error {
+	if !bytes.HasPrefix(b, headMagic) {
+		return ErrMagic
+	}
+	if len(b) < 19 {
+		return ErrShort
+	}
+
+	*h = Head{
+		Version:       b[8],
+		Channels:      b[9],
+		PreSkip:       binary.LittleEndian.Uint16(b[10:]),
+		SampleRate:    binary.LittleEndian.Uint32(b[12:]),
+		OutputGain:    int16(binary.LittleEndian.Uint16(b[16:])),
+		MappingFamily: b[18],
+	}
+	if h.MappingFamily != 0 {
+		if len(b) < 21+int(h.Channels) {
+			return ErrShort
+		}
+		h.StreamCount = b[19]
+		h.CoupledCount = b[20]
+		h.Mapping = append([]uint8(nil), b[21:21+int(h.Channels)]...)
+	}
+	return h.Validate()
+}
+
+// Tags is the comment header, OpusTags, that follows the OpusHead.
+type Tags struct {
+	comment.Header
+	// Extra is binary data following the comments.
+	// Per RFC 7845, it's only kept if the lowest bit of its first byte is set;
+	// otherwise it's padding.
+	Extra []byte
+}
+
+// MarshalBinary encodes t as an OpusTags packet.
+func (t *Tags) MarshalBinary() ([]byte, error) {
+	b := append([]byte(nil), tagsMagic...)
+	b = t.Header.Append(b)
+	b = append(b, t.Extra...)
+	return b, nil
+}
+
+// UnmarshalBinary decodes an OpusTags packet into t.
+func (t *Tags) UnmarshalBinary(b []byte) error {
+	if !bytes.HasPrefix(b, tagsMagic) {
+		return ErrMagic
+	}
+	h, n, err := comment.Parse(b[len(tagsMagic):])
+	if err != nil {
+		return err
+	}
+
+	*t = Tags{Header: h}
+	rest := b[len(tagsMagic)+n:]
+	if len(rest) > 0 && rest[0]&1 != 0 {
+		t.Extra = append([]byte(nil), rest...)
+	}
+	return nil
+}
+
+// A Reader reads the audio packets of an Ogg Opus stream.
+type Reader struct {
+	// Serial is the Opus stream's bitstream serial number.
+	Serial uint32
+	Head   Head
+	Tags   Tags
+
+	s *ogg.LogicalStream
+}
+
+// NewReader reads the headers of the first Opus stream in r.
+// Other logical streams in r are ignored.
+func NewReader(r io.Reader) (*Reader, error) {
+	d := ogg.NewDemuxer(r)
+	s, p, err := d.FindStream(func(first []byte) bool {
+		return bytes.HasPrefix(first, headMagic)
+	})
+	if err == io.EOF {
+		return nil, ErrNoStream
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	or := &Reader{Serial: s.Serial, s: s}
+	err = or.Head.UnmarshalBinary(p.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err = s.ReadPacket()
+	if err != nil {
+		return nil, err
+	}
+	err = or.Tags.UnmarshalBinary(p.Data)
+	if err != nil {
+		return nil, err
+	}
+	return or, nil
+}
+
+// ReadPacket returns the next audio packet.
+// The error is io.EOF after the last packet.
+func (r *Reader) ReadPacket() (ogg.Packet, error) {
+	return r.s.ReadPacket()
+}
+
+// A Writer writes an Ogg Opus stream.
+type Writer struct {
+	pw *ogg.PacketWriter
+}
+
+// NewWriter creates a Writer of an Ogg Opus stream with the given serial,
+// writing the headers immediately, each on their own pages as RFC 7845 requires.
+func NewWriter(w io.Writer, serial uint32, head *Head, tags *Tags, policy ogg.PagePolicy) (*Writer, error) {
+	hb, err := head.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	tb, err := tags.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	pw := ogg.NewPacketWriter(serial, w, policy)
+	err = pw.WritePacket(0, hb)
+	if err != nil {
+		return nil, err
+	}
+	err = pw.WritePacket(0, tb)
+	if err != nil {
+		return nil, err
+	}
+	err = pw.Flush()
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{pw}, nil
+}
+
+// WritePacket writes an audio packet,
+// whose granule position is the number of 48 kHz samples decoded
+// through the end of the packet, including PreSkip.
+func (w *Writer) WritePacket(granule int64, packet []byte) error {
+	return w.pw.WritePacket(granule, packet)
+}
+
+// WriteLastPacket writes an audio packet like WritePacket, and ends the stream with it.
+// It does not close the underlying Writer.
+func (w *Writer) WriteLastPacket(granule int64, packet []byte) error {
+	return w.pw.WriteLastPacket(granule, packet)
+}
+
+// Close writes any buffered packets and ends the stream.
+// If no packets are buffered, the end-of-stream page has an empty packet,
+// which RFC 7845 forbids, so WriteLastPacket is preferable.
+// It does not close the underlying Writer.
+func (w *Writer) Close() error {
+	return w.pw.Close()
+}
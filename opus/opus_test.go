@@ -0,0 +1,207 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package opus
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"mccoy.space/g/ogg"
+	"mccoy.space/g/ogg/comment"
+)
+
+func TestHeadRoundTrip(t *testing.T) {
+	heads := []Head{
+		{Version: 1, Channels: 2, PreSkip: 312, SampleRate: 44100, OutputGain: -256},
+		{Version: 1, Channels: 6, PreSkip: 3840, SampleRate: 48000, MappingFamily: 1,
+			StreamCount: 4, CoupledCount: 2, Mapping: []uint8{0, 4, 1, 2, 3, 5}},
+		{Version: 15, Channels: 3, MappingFamily: 255, StreamCount: 1, Mapping: []uint8{0, 255, 0}},
+	}
+	for _, h := range heads {
+		b, err := h.MarshalBinary()
+		if err != nil {
+			t.Fatalf("unexpected MarshalBinary error for %+v: %v", h, err)
+		}
+
+		var got Head
+		err = got.UnmarshalBinary(b)
+		if err != nil {
+			t.Fatalf("unexpected UnmarshalBinary error for %+v: %v", h, err)
+		}
+		if !reflect.DeepEqual(got, h) {
+			t.Fatalf("expected %+v, got %+v", h, got)
+		}
+	}
+
+	b, _ := heads[0].MarshalBinary()
+	expect := []byte("OpusHead\x01\x02\x38\x01\x44\xac\x00\x00\x00\xff\x00")
+	if !bytes.Equal(b, expect) {
+		t.Fatalf("bytes are wrong:\n%q\nexpected\n%q", b, expect)
+	}
+}
+
+func TestHeadValidate(t *testing.T) {
+	tests := []struct {
+		h   Head
+		err error
+	}{
+		{Head{Version: 16, Channels: 1}, ErrVersion},
+		{Head{Version: 1}, ErrChannels},
+		{Head{Version: 1, Channels: 3}, ErrChannels},
+		{Head{Version: 1, Channels: 9, MappingFamily: 1, StreamCount: 9, Mapping: make([]uint8, 9)}, ErrChannels},
+		{Head{Version: 1, Channels: 2, MappingFamily: 1, Mapping: []uint8{0, 0}}, ErrMapping},
+		{Head{Version: 1, Channels: 2, MappingFamily: 1, StreamCount: 1, CoupledCount: 2, Mapping: []uint8{0, 1}}, ErrMapping},
+		{Head{Version: 1, Channels: 2, MappingFamily: 1, StreamCount: 1, Mapping: []uint8{0, 1}}, ErrMapping},
+		{Head{Version: 1, Channels: 2, MappingFamily: 1, StreamCount: 1, Mapping: []uint8{0}}, ErrMapping},
+		{Head{Version: 1, Channels: 2, MappingFamily: 1, StreamCount: 1, CoupledCount: 1, Mapping: []uint8{0, 1}}, nil},
+	}
+	for _, x := range tests {
+		err := x.h.Validate()
+		if err != x.err {
+			t.Fatalf("expected %v for %+v, got %v", x.err, x.h, err)
+		}
+	}
+
+	var h Head
+	err := h.UnmarshalBinary([]byte("OpusHead\x01\x02"))
+	if err != ErrShort {
+		t.Fatal("expected ErrShort, got", err)
+	}
+	err = h.UnmarshalBinary([]byte("OpusTags"))
+	if err != ErrMagic {
+		t.Fatal("expected ErrMagic, got", err)
+	}
+}
+
+func TestTagsRoundTrip(t *testing.T) {
+	tags := Tags{Header: comment.Header{Vendor: "test"}}
+	tags.Add("TITLE", "Song")
+	tags.Add("ARTIST", "Band")
+
+	b, err := tags.MarshalBinary()
+	if err != nil {
+		t.Fatal("unexpected MarshalBinary error:", err)
+	}
+
+	var got Tags
+	err = got.UnmarshalBinary(b)
+	if err != nil {
+		t.Fatal("unexpected UnmarshalBinary error:", err)
+	}
+	if !reflect.DeepEqual(got, tags) {
+		t.Fatalf("expected %+v, got %+v", tags, got)
+	}
+	if v := got.Get("title"); len(v) != 1 || v[0] != "Song" {
+		t.Fatal("wrong title:", v)
+	}
+
+	// Padding is discarded, and binary data is kept.
+	err = got.UnmarshalBinary(append(b, 0, 0, 0))
+	if err != nil {
+		t.Fatal("unexpected UnmarshalBinary error:", err)
+	}
+	if got.Extra != nil {
+		t.Fatal("expected padding to be discarded, got", got.Extra)
+	}
+	err = got.UnmarshalBinary(append(b, 1, 2, 3))
+	if err != nil {
+		t.Fatal("unexpected UnmarshalBinary error:", err)
+	}
+	if !bytes.Equal(got.Extra, []byte{1, 2, 3}) {
+		t.Fatal("expected binary data to be kept, got", got.Extra)
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	head := Head{Version: 1, Channels: 2, PreSkip: 312, SampleRate: 48000}
+	tags := Tags{Header: comment.Header{Vendor: "test", Comments: []string{"TITLE=Song"}}}
+
+	var b bytes.Buffer
+	other := ogg.NewEncoder(2, &b)
+	err := other.EncodeBOS(0, [][]byte{[]byte("\x80theora")})
+	if err != nil {
+		t.Fatal("unexpected EncodeBOS error:", err)
+	}
+
+	w, err := NewWriter(&b, 1, &head, &tags, ogg.PagePolicy{})
+	if err != nil {
+		t.Fatal("unexpected NewWriter error:", err)
+	}
+	for i := 1; i < 100; i++ {
+		err = w.WritePacket(312+int64(i)*960, []byte{byte(i)})
+		if err != nil {
+			t.Fatal("unexpected WritePacket error:", err)
+		}
+	}
+	err = w.WriteLastPacket(312+100*960, []byte{100})
+	if err != nil {
+		t.Fatal("unexpected WriteLastPacket error:", err)
+	}
+	err = other.EncodeEOS(0, nil)
+	if err != nil {
+		t.Fatal("unexpected EncodeEOS error:", err)
+	}
+
+	// The headers must each be alone on their pages.
+	d := ogg.NewDecoder(bytes.NewReader(b.Bytes()))
+	var headerPages int
+	for {
+		p, err := d.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal("unexpected Decode error:", err)
+		}
+		if p.Serial == 1 && p.Granule == 0 {
+			headerPages++
+			if len(p.Packets) != 1 {
+				t.Fatalf("expected a header page with 1 packet, got %d", len(p.Packets))
+			}
+		}
+	}
+	if headerPages != 2 {
+		t.Fatalf("expected 2 header pages, got %d", headerPages)
+	}
+
+	r, err := NewReader(&b)
+	if err != nil {
+		t.Fatal("unexpected NewReader error:", err)
+	}
+	if r.Serial != 1 || !reflect.DeepEqual(r.Head, head) || !reflect.DeepEqual(r.Tags, tags) {
+		t.Fatalf("headers are wrong: %d %+v %+v", r.Serial, r.Head, r.Tags)
+	}
+	for i := 1; i <= 100; i++ {
+		p, err := r.ReadPacket()
+		if err != nil {
+			t.Fatal("unexpected ReadPacket error:", err)
+		}
+		if len(p.Data) != 1 || p.Data[0] != byte(i) {
+			t.Fatalf("packet %d is wrong: %v", i, p.Data)
+		}
+	}
+	p, err := r.ReadPacket()
+	if err != io.EOF {
+		t.Fatalf("expected EOF, got %v %v", p, err)
+	}
+}
+
+func TestReadNoStream(t *testing.T) {
+	var b bytes.Buffer
+	e := ogg.NewEncoder(1, &b)
+	err := e.EncodeBOS(0, [][]byte{[]byte("\x01vorbis")})
+	if err != nil {
+		t.Fatal("unexpected EncodeBOS error:", err)
+	}
+	err = e.EncodeEOS(0, nil)
+	if err != nil {
+		t.Fatal("unexpected EncodeEOS error:", err)
+	}
+
+	_, err = NewReader(&b)
+	if err != ErrNoStream {
+		t.Fatal("expected ErrNoStream, got", err)
+	}
+}
@@ -0,0 +1,172 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package ogg
+
+import (
+	"io"
+	"strconv"
+)
+
+// A Packet is a complete packet from a logical stream,
+// reassembled from however many pages it spans.
+type Packet struct {
+	// Serial is the bitstream serial number of the packet's logical stream.
+	Serial uint32
+	// Granule is the granule position of the page on which the packet completes.
+	Granule int64
+	// Index counts the packets read from the logical stream, starting at 0.
+	Index int64
+	// BOS is true for the first packet of a logical stream.
+	BOS bool
+	// EOS is true for the last packet of a logical stream.
+	// If the stream's EOS page completes no packet, as in a damaged stream,
+	// none of its packets has EOS set.
+	EOS bool
+	// Data is the raw packet data.
+	Data []byte
+}
+
+// ErrMissingContinuation is the error used by a PacketReader when
+// a page's continuation flag disagrees with its logical stream:
+// either a packet was left incomplete and the stream's next page doesn't continue it,
+// or a page continues a packet whose beginning was never read.
+// The incomplete packet is discarded.
+type ErrMissingContinuation struct {
+	Serial uint32
+}
+
+func (mc ErrMissingContinuation) Error() string {
+	return "missing packet continuation in stream " + strconv.FormatUint(uint64(mc.Serial), 16)
+}
+
+// A PacketReader reads whole packets from an ogg stream,
+// joining the pieces of packets that are split across pages.
+type PacketReader struct {
+	d       *Decoder
+	streams map[uint32]*packetStream
+	queue   []Packet
+	// ended, if not nil, is called with the serial of a stream whose EOS page completes no packet.
+	ended func(serial uint32)
+	// skip is how many packets of the stream with serial skipSerial to discard.
+	skipSerial uint32
+	skip       int
+}
+
+// packetStream is the reassembly state of one logical stream.
+type packetStream struct {
+	partial    []byte
+	continuing bool
+	dropping   bool // skipping the rest of a packet whose start was missed
+	index      int64
+}
+
+// NewPacketReader creates a PacketReader that decodes pages from r.
+func NewPacketReader(r io.Reader) *PacketReader {
+	return newPacketReader(NewDecoder(r))
+}
+
+func newPacketReader(d *Decoder) *PacketReader {
+	return &PacketReader{
+		d:       d,
+		streams: make(map[uint32]*packetStream),
+	}
+}
+
+// ReadPacket returns the next complete packet from any logical stream,
+// in the order that the packets complete in the physical stream.
+// The error may be io.EOF if that's what the underlying Reader returned.
+//
+// If the error is an ErrMissingContinuation, the PacketReader dropped
+// an incomplete packet and subsequent calls continue with the following packets.
+//
+// Unlike a Page's Packets, the returned Packet's Data is not reused by the PacketReader.
+func (r *PacketReader) ReadPacket() (Packet, error) {
+	for len(r.queue) == 0 {
+		page, err := r.d.Decode()
+		if err != nil {
+			return Packet{}, err
+		}
+
+		err = r.push(page)
+		if err != nil {
+			return Packet{}, err
+		}
+	}
+
+	p := r.queue[0]
+	r.queue[0] = Packet{}
+	r.queue = r.queue[1:]
+	return p, nil
+}
+
+// push queues the packets completed by page.
+// The returned error, if any, only concerns the page's first packet;
+// the rest are still queued.
+func (r *PacketReader) push(page Page) error {
+	s := r.streams[page.Serial]
+	known := s != nil
+	if !known {
+		s = &packetStream{}
+		r.streams[page.Serial] = s
+	}
+
+	var err error
+	packets := page.Packets
+	cop := page.Type&COP != 0
+	if s.continuing && !cop {
+		err = ErrMissingContinuation{page.Serial}
+		s.partial = nil
+		s.continuing = false
+	} else if !s.continuing && cop && len(packets) > 0 {
+		// The start of this packet was never seen, which is only expected
+		// when starting to read from the middle of a stream.
+		if known && !s.dropping {
+			err = ErrMissingContinuation{page.Serial}
+		}
+		s.dropping = len(packets) == 1 && page.Continued
+		packets = packets[1:]
+	} else if !cop {
+		s.dropping = false
+	}
+
+	last := -1
+	for i, p := range packets {
+		s.partial = append(s.partial, p...)
+		if i == len(packets)-1 && page.Continued {
+			s.continuing = true
+			break
+		}
+
+		s.continuing = false
+		data := s.partial
+		if data == nil {
+			data = []byte{}
+		}
+		s.partial = nil
+		if page.Serial == r.skipSerial && r.skip > 0 {
+			r.skip--
+			continue
+		}
+
+		r.queue = append(r.queue, Packet{
+			Serial:  page.Serial,
+			Granule: page.Granule,
+			Index:   s.index,
+			BOS:     s.index == 0 && page.Type&BOS != 0,
+			Data:    data,
+		})
+		s.index++
+		last = len(r.queue) - 1
+	}
+
+	if page.Type&EOS != 0 {
+		if last >= 0 {
+			r.queue[last].EOS = true
+		} else if r.ended != nil {
+			r.ended(page.Serial)
+		}
+		delete(r.streams, page.Serial)
+	}
+
+	return err
+}
@@ -0,0 +1,176 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package ogg
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+func TestBasicReadPacket(t *testing.T) {
+	var b bytes.Buffer
+	e := NewEncoder(1, &b)
+
+	err := e.EncodeBOS(2, [][]byte{[]byte("hello")})
+	if err != nil {
+		t.Fatal("unexpected EncodeBOS error:", err)
+	}
+	err = e.Encode(3, [][]byte{[]byte("there"), []byte("friend")})
+	if err != nil {
+		t.Fatal("unexpected Encode error:", err)
+	}
+	err = e.EncodeEOS(4, [][]byte{[]byte("bye")})
+	if err != nil {
+		t.Fatal("unexpected EncodeEOS error:", err)
+	}
+
+	expect := []Packet{
+		{Serial: 1, Granule: 2, Index: 0, BOS: true, Data: []byte("hello")},
+		{Serial: 1, Granule: 3, Index: 1, Data: []byte("there")},
+		{Serial: 1, Granule: 3, Index: 2, Data: []byte("friend")},
+		{Serial: 1, Granule: 4, Index: 3, EOS: true, Data: []byte("bye")},
+	}
+
+	r := NewPacketReader(&b)
+	for i, x := range expect {
+		p, err := r.ReadPacket()
+		if err != nil {
+			t.Fatalf("unexpected ReadPacket error on packet %d: %v", i, err)
+		}
+		if p.Serial != x.Serial || p.Granule != x.Granule || p.Index != x.Index || p.BOS != x.BOS || p.EOS != x.EOS {
+			t.Fatalf("packet %d is wrong: %+v", i, p)
+		}
+		if !bytes.Equal(p.Data, x.Data) {
+			t.Fatalf("packet %d bytes != expected:\n%x\n%x", i, p.Data, x.Data)
+		}
+	}
+
+	_, err = r.ReadPacket()
+	if err != io.EOF {
+		t.Fatal("expected EOF, got:", err)
+	}
+}
+
+func TestLongReadPacket(t *testing.T) {
+	var b bytes.Buffer
+	e1 := NewEncoder(1, &b)
+	e2 := NewEncoder(2, &b)
+
+	var junk bytes.Buffer
+	for i := 0; i < mps*30+17; i++ {
+		c := byte(rand.Intn(26)) + 'a'
+		junk.WriteByte(c)
+	}
+
+	err := e1.EncodeBOS(0, [][]byte{[]byte("one")})
+	if err != nil {
+		t.Fatal("unexpected EncodeBOS error:", err)
+	}
+	err = e2.EncodeBOS(0, [][]byte{[]byte("two")})
+	if err != nil {
+		t.Fatal("unexpected EncodeBOS error:", err)
+	}
+	err = e1.Encode(5, [][]byte{[]byte("a"), junk.Bytes(), []byte("b")})
+	if err != nil {
+		t.Fatal("unexpected Encode error:", err)
+	}
+	err = e2.EncodeEOS(6, nil)
+	if err != nil {
+		t.Fatal("unexpected EncodeEOS error:", err)
+	}
+
+	expect := []Packet{
+		{Serial: 1, Index: 0, BOS: true, Data: []byte("one")},
+		{Serial: 2, Index: 0, BOS: true, Data: []byte("two")},
+		{Serial: 1, Granule: 5, Index: 1, Data: []byte("a")},
+		{Serial: 1, Granule: 5, Index: 2, Data: junk.Bytes()},
+		{Serial: 1, Granule: 5, Index: 3, Data: []byte("b")},
+		{Serial: 2, Granule: 6, Index: 1, EOS: true, Data: []byte{}},
+	}
+
+	r := NewPacketReader(&b)
+	for i, x := range expect {
+		p, err := r.ReadPacket()
+		if err != nil {
+			t.Fatalf("unexpected ReadPacket error on packet %d: %v", i, err)
+		}
+		if p.Serial != x.Serial || p.Index != x.Index || p.BOS != x.BOS || p.EOS != x.EOS {
+			t.Fatalf("packet %d is wrong: %+v", i, p)
+		}
+		if p.Granule != x.Granule && p.Serial == 2 {
+			t.Fatalf("packet %d has granule %d, expected %d", i, p.Granule, x.Granule)
+		}
+		if !bytes.Equal(p.Data, x.Data) {
+			t.Fatalf("packet %d is wrong size: %d vs. %d", i, len(p.Data), len(x.Data))
+		}
+	}
+}
+
+func TestMissingContinuation(t *testing.T) {
+	var b bytes.Buffer
+	e := NewEncoder(1, &b)
+
+	var junk bytes.Buffer
+	for i := 0; i < mps*3-1000; i++ {
+		junk.WriteByte('x')
+	}
+
+	err := e.Encode(2, [][]byte{junk.Bytes()})
+	if err != nil {
+		t.Fatal("unexpected Encode error:", err)
+	}
+
+	// Drop the end of the long packet.
+	bb := append([]byte(nil), b.Bytes()[:maxPageSize*2]...)
+
+	b.Reset()
+	err = e.Encode(3, [][]byte{[]byte("hello")})
+	if err != nil {
+		t.Fatal("unexpected Encode error:", err)
+	}
+	bb = append(bb, b.Bytes()...)
+
+	r := NewPacketReader(bytes.NewReader(bb))
+	_, err = r.ReadPacket()
+	if _, ok := err.(ErrMissingContinuation); !ok {
+		t.Fatal("expected ErrMissingContinuation, got:", err)
+	}
+
+	p, err := r.ReadPacket()
+	if err != nil {
+		t.Fatal("unexpected ReadPacket error:", err)
+	}
+	if !bytes.Equal(p.Data, []byte("hello")) {
+		t.Fatalf("bytes != expected:\n%x\n%x", p.Data, []byte("hello"))
+	}
+}
+
+func TestMidstreamReadPacket(t *testing.T) {
+	var b bytes.Buffer
+	e := NewEncoder(1, &b)
+
+	var junk bytes.Buffer
+	for i := 0; i < mps*3-1000; i++ {
+		junk.WriteByte('x')
+	}
+
+	err := e.Encode(2, [][]byte{junk.Bytes(), []byte("hello")})
+	if err != nil {
+		t.Fatal("unexpected Encode error:", err)
+	}
+
+	// Start reading at the second page, as if after a seek.
+	r := NewPacketReader(bytes.NewReader(b.Bytes()[maxPageSize:]))
+	p, err := r.ReadPacket()
+	if err != nil {
+		t.Fatal("unexpected ReadPacket error:", err)
+	}
+	if !bytes.Equal(p.Data, []byte("hello")) {
+		t.Fatalf("bytes != expected:\n%x\n%x", p.Data, []byte("hello"))
+	}
+	if p.Index != 0 {
+		t.Fatal("expected index 0, got", p.Index)
+	}
+}
@@ -0,0 +1,220 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package ogg
+
+import (
+	"errors"
+	"io"
+)
+
+// the payload size at which pages are emitted if a PagePolicy doesn't say, as in libogg
+const defaultPageSize = 4096
+
+// A PagePolicy decides when a PacketWriter emits a page.
+// A page is emitted as soon as any of the limits is reached,
+// or when its segment table is full.
+// Zero values mean no limit, except for MaxSize.
+type PagePolicy struct {
+	// MaxSize is the payload size, in bytes, at which a page is emitted.
+	// Packets are split across pages to keep them near this size.
+	// If it is 0, 4096 is used.
+	MaxSize int
+	// MaxPackets is the number of completed packets at which a page is emitted.
+	MaxPackets int
+	// MaxGranule is the granule position distance from the previous page
+	// at which a page is emitted.
+	// Its meaning depends on the encapsulated codec; for audio it's usually a number of samples.
+	MaxGranule int64
+}
+
+// ErrClosed is the error used when writing to a closed PacketWriter.
+var ErrClosed = errors.New("write to closed PacketWriter")
+
+// A PacketWriter encodes packets into a logical ogg stream,
+// buffering them and deciding page boundaries itself, like libogg's ogg_stream_pageout.
+type PacketWriter struct {
+	e      *Encoder
+	policy PagePolicy
+
+	// buffered, unwritten data: lacing values,
+	// the granule position for each value that ends a packet (or -1),
+	// and the packet bytes
+	lacing   []byte
+	granules []int64
+	body     []byte
+
+	begun       bool  // whether the BOS page was written
+	cont        bool  // whether the first lacing value continues a written packet
+	granule     int64 // granule position of the last packet
+	prevGranule int64 // granule position of the last page that completed a packet
+	closed      bool
+}
+
+// NewPacketWriter creates a PacketWriter for the logical stream with the given serial ID,
+// emitting pages according to policy.
+// See NewEncoder for the rules about multiplexing streams.
+func NewPacketWriter(id uint32, w io.Writer, policy PagePolicy) *PacketWriter {
+	return &PacketWriter{
+		e:           NewEncoder(id, w),
+		policy:      policy,
+		prevGranule: -1,
+	}
+}
+
+// WritePacket buffers a packet with the given granule position,
+// writing pages to the underlying Writer when the PagePolicy says to.
+// The first packet is written immediately, alone on the beginning-of-stream page.
+// The packet is copied, so the caller may reuse it.
+func (w *PacketWriter) WritePacket(granule int64, packet []byte) error {
+	if w.closed {
+		return ErrClosed
+	}
+	w.buffer(granule, packet)
+	return w.pageout(!w.begun, false)
+}
+
+// WriteLastPacket writes a packet like WritePacket, then all buffered packets,
+// marking the page that completes it as end-of-stream, like libogg's e_o_s flag.
+// The PacketWriter is then closed.
+func (w *PacketWriter) WriteLastPacket(granule int64, packet []byte) error {
+	if w.closed {
+		return ErrClosed
+	}
+	w.closed = true
+	w.buffer(granule, packet)
+	return w.pageout(true, true)
+}
+
+// buffer appends a packet's lacing values and data to the buffer.
+func (w *PacketWriter) buffer(granule int64, packet []byte) {
+	n := len(packet)
+	for ; n >= mss; n -= mss {
+		w.lacing = append(w.lacing, mss)
+		w.granules = append(w.granules, -1)
+	}
+	w.lacing = append(w.lacing, byte(n))
+	w.granules = append(w.granules, granule)
+	w.body = append(w.body, packet...)
+	w.granule = granule
+}
+
+// Flush writes all buffered packets, in as many pages as necessary,
+// like libogg's ogg_stream_flush.
+func (w *PacketWriter) Flush() error {
+	if w.closed {
+		return ErrClosed
+	}
+	return w.pageout(true, false)
+}
+
+// Close writes all buffered packets, marking the last page as end-of-stream.
+// If there are no buffered packets, an empty packet is written on the end-of-stream page;
+// since some codecs forbid empty packets, WriteLastPacket is preferable when the last packet is known.
+// Close does not close the underlying Writer.
+func (w *PacketWriter) Close() error {
+	if w.closed {
+		return ErrClosed
+	}
+	w.closed = true
+
+	if len(w.lacing) == 0 {
+		w.lacing = append(w.lacing, 0)
+		w.granules = append(w.granules, w.granule)
+	}
+	return w.pageout(true, true)
+}
+
+// pageout writes pages while the policy says a page is full.
+// If force is true, all buffered data is written.
+// If eos is true, the final page is marked end-of-stream.
+func (w *PacketWriter) pageout(force, eos bool) error {
+	for len(w.lacing) > 0 {
+		n, granule, ok := w.fill(force)
+		if !ok {
+			return nil
+		}
+
+		err := w.writePage(n, granule, eos && n == len(w.lacing))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fill determines how many lacing values belong on the next page,
+// and its granule position.
+// It returns false if the policy says to keep buffering.
+func (w *PacketWriter) fill(force bool) (int, int64, bool) {
+	maxSize := w.policy.MaxSize
+	if maxSize <= 0 {
+		maxSize = defaultPageSize
+	}
+
+	size := 0
+	packets := 0
+	granule := int64(-1)
+	for n, l := range w.lacing {
+		if n == mss {
+			return n, granule, true
+		}
+
+		size += int(l)
+		if l < mss {
+			packets++
+			granule = w.granules[n]
+			if w.policy.MaxPackets > 0 && packets >= w.policy.MaxPackets {
+				return n + 1, granule, true
+			}
+			if w.policy.MaxGranule > 0 && w.prevGranule >= 0 && granule-w.prevGranule >= w.policy.MaxGranule {
+				return n + 1, granule, true
+			}
+		}
+		if size >= maxSize {
+			return n + 1, granule, true
+		}
+	}
+
+	if force || len(w.lacing) == mss {
+		return len(w.lacing), granule, true
+	}
+	return 0, 0, false
+}
+
+// writePage writes the first n buffered lacing values and their data as one page.
+func (w *PacketWriter) writePage(n int, granule int64, eos bool) error {
+	h := pageHeader{
+		OggS:    [4]byte{'O', 'g', 'g', 'S'},
+		Serial:  w.e.serial,
+		Granule: granule,
+	}
+	if w.cont {
+		h.HeaderType |= COP
+	}
+	if !w.begun {
+		h.HeaderType |= BOS
+	}
+	if eos {
+		h.HeaderType |= EOS
+	}
+
+	size := 0
+	for _, l := range w.lacing[:n] {
+		size += int(l)
+	}
+
+	err := w.e.writePage(&h, w.lacing[:n], payload{w.body[:size], nil, nil})
+	if err != nil {
+		return err
+	}
+
+	w.begun = true
+	w.cont = w.lacing[n-1] == mss
+	if granule >= 0 {
+		w.prevGranule = granule
+	}
+	w.lacing = w.lacing[:copy(w.lacing, w.lacing[n:])]
+	w.granules = w.granules[:copy(w.granules, w.granules[n:])]
+	w.body = w.body[:copy(w.body, w.body[size:])]
+	return nil
+}
@@ -0,0 +1,249 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package ogg
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func decodeAll(t *testing.T, r io.Reader) []Page {
+	var pages []Page
+	d := NewDecoder(r)
+	for {
+		p, err := d.Decode()
+		if err == io.EOF {
+			return pages
+		}
+		if err != nil {
+			t.Fatal("unexpected Decode error:", err)
+		}
+
+		// The Decoder reuses its buffer
+		packets := make([][]byte, len(p.Packets))
+		for i := range p.Packets {
+			packets[i] = append([]byte{}, p.Packets[i]...)
+		}
+		p.Packets = packets
+		p.Segments = append([]byte{}, p.Segments...)
+		pages = append(pages, p)
+	}
+}
+
+func TestPacketWriterPages(t *testing.T) {
+	var b bytes.Buffer
+	w := NewPacketWriter(1, &b, PagePolicy{MaxPackets: 3})
+
+	for i := 0; i < 8; i++ {
+		err := w.WritePacket(int64(i*10), []byte{byte(i)})
+		if err != nil {
+			t.Fatal("unexpected WritePacket error:", err)
+		}
+	}
+	err := w.Close()
+	if err != nil {
+		t.Fatal("unexpected Close error:", err)
+	}
+
+	pages := decodeAll(t, &b)
+	expect := []struct {
+		typ     byte
+		granule int64
+		packets int
+	}{
+		{BOS, 0, 1},
+		{0, 30, 3},
+		{0, 60, 3},
+		{EOS, 70, 1},
+	}
+	if len(pages) != len(expect) {
+		t.Fatalf("expected %d pages, got %d", len(expect), len(pages))
+	}
+	for i, x := range expect {
+		p := pages[i]
+		if p.Type != x.typ || p.Granule != x.granule || len(p.Packets) != x.packets {
+			t.Fatalf("page %d is wrong: type %d, granule %d, %d packets", i, p.Type, p.Granule, len(p.Packets))
+		}
+		if p.Sequence != uint32(i) {
+			t.Fatalf("page %d has sequence number %d", i, p.Sequence)
+		}
+	}
+
+	err = w.WritePacket(80, []byte{8})
+	if err != ErrClosed {
+		t.Fatal("expected ErrClosed, got:", err)
+	}
+}
+
+func TestPacketWriterSize(t *testing.T) {
+	var b bytes.Buffer
+	w := NewPacketWriter(1, &b, PagePolicy{MaxSize: 1000, MaxGranule: 1000})
+
+	err := w.WritePacket(0, []byte("head"))
+	if err != nil {
+		t.Fatal("unexpected WritePacket error:", err)
+	}
+
+	packet := bytes.Repeat([]byte{'x'}, 300)
+	for i := 1; i <= 6; i++ {
+		err := w.WritePacket(int64(i*100), packet)
+		if err != nil {
+			t.Fatal("unexpected WritePacket error:", err)
+		}
+	}
+
+	// 3 packets fill a page, and the 4th is split
+	pages := decodeAll(t, bytes.NewReader(b.Bytes()))
+	if len(pages) != 2 {
+		t.Fatalf("expected 2 pages, got %d", len(pages))
+	}
+	p := pages[1]
+	if p.Granule != 300 || len(p.Packets) != 4 || !p.Continued {
+		t.Fatalf("page is wrong: granule %d, %d packets, continued %v", p.Granule, len(p.Packets), p.Continued)
+	}
+
+	err = w.Flush()
+	if err != nil {
+		t.Fatal("unexpected Flush error:", err)
+	}
+	pages = decodeAll(t, bytes.NewReader(b.Bytes()))
+	if len(pages) != 3 {
+		t.Fatalf("expected 3 pages, got %d", len(pages))
+	}
+	p = pages[2]
+	if p.Type != COP || p.Granule != 600 || len(p.Packets) != 3 || p.Continued {
+		t.Fatalf("page is wrong: type %d, granule %d, %d packets, continued %v", p.Type, p.Granule, len(p.Packets), p.Continued)
+	}
+
+	// the empty packet for EOS
+	err = w.Close()
+	if err != nil {
+		t.Fatal("unexpected Close error:", err)
+	}
+	pages = decodeAll(t, bytes.NewReader(b.Bytes()))
+	p = pages[len(pages)-1]
+	if p.Type != EOS || p.Granule != 600 || len(p.Packets) != 1 || len(p.Packets[0]) != 0 {
+		t.Fatalf("page is wrong: type %d, granule %d, %d packets", p.Type, p.Granule, len(p.Packets))
+	}
+}
+
+func TestPacketWriterLast(t *testing.T) {
+	var b bytes.Buffer
+	w := NewPacketWriter(1, &b, PagePolicy{})
+	steps := []struct {
+		granule int64
+		packet  string
+		flush   bool
+		pages   int
+	}{
+		{0, "head", false, 1},
+		{0, "tags", true, 2},
+		{960, "data", false, 2},
+		{1920, "more", true, 3},
+	}
+	for i, x := range steps {
+		err := w.WritePacket(x.granule, []byte(x.packet))
+		if err != nil {
+			t.Fatal("unexpected WritePacket error:", err)
+		}
+		if x.flush {
+			err = w.Flush()
+			if err != nil {
+				t.Fatal("unexpected Flush error:", err)
+			}
+		}
+		// Flushed pages are in the Writer right away.
+		if n := len(decodeAll(t, bytes.NewReader(b.Bytes()))); n != x.pages {
+			t.Fatalf("after step %d, expected %d pages, got %d", i, x.pages, n)
+		}
+	}
+	err := w.WriteLastPacket(2880, []byte("last"))
+	if err != nil {
+		t.Fatal("unexpected WriteLastPacket error:", err)
+	}
+	err = w.WritePacket(3840, []byte("late"))
+	if err != ErrClosed {
+		t.Fatal("expected ErrClosed, got", err)
+	}
+
+	// The EOS page holds only the last packet, with no empty packet after it.
+	pages := decodeAll(t, &b)
+	if len(pages) != 4 {
+		t.Fatalf("expected 4 pages, got %d", len(pages))
+	}
+	p := pages[3]
+	if p.Type != EOS || p.Granule != 2880 || len(p.Packets) != 1 || string(p.Packets[0]) != "last" {
+		t.Fatalf("page is wrong: type %d, granule %d, packets %q", p.Type, p.Granule, p.Packets)
+	}
+}
+
+func TestPacketWriterGranule(t *testing.T) {
+	var b bytes.Buffer
+	w := NewPacketWriter(1, &b, PagePolicy{MaxGranule: 960 * 3})
+
+	for i := 0; i < 8; i++ {
+		err := w.WritePacket(int64(i*960), []byte("opus"))
+		if err != nil {
+			t.Fatal("unexpected WritePacket error:", err)
+		}
+	}
+
+	pages := decodeAll(t, &b)
+	if len(pages) != 3 {
+		t.Fatalf("expected 3 pages, got %d", len(pages))
+	}
+	if pages[1].Granule != 960*3 || pages[2].Granule != 960*6 {
+		t.Fatalf("granules are wrong: %d, %d", pages[1].Granule, pages[2].Granule)
+	}
+}
+
+func TestPacketWriterLong(t *testing.T) {
+	var b bytes.Buffer
+	w := NewPacketWriter(1, &b, PagePolicy{MaxSize: mps * 2})
+
+	var junk bytes.Buffer
+	for i := 0; i < mps*2+10; i++ {
+		junk.WriteByte(byte(i))
+	}
+
+	err := w.WritePacket(0, []byte("head"))
+	if err != nil {
+		t.Fatal("unexpected WritePacket error:", err)
+	}
+	err = w.WritePacket(7, junk.Bytes())
+	if err != nil {
+		t.Fatal("unexpected WritePacket error:", err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatal("unexpected Close error:", err)
+	}
+
+	pages := decodeAll(t, bytes.NewReader(b.Bytes()))
+	if len(pages) != 4 {
+		t.Fatalf("expected 4 pages, got %d", len(pages))
+	}
+	expect := []int64{0, -1, -1, 7}
+	for i, p := range pages {
+		if p.Granule != expect[i] {
+			t.Fatalf("page %d has granule %d, expected %d", i, p.Granule, expect[i])
+		}
+	}
+	if pages[3].Type != COP|EOS {
+		t.Fatal("unexpected page type:", pages[3].Type)
+	}
+
+	r := NewPacketReader(&b)
+	_, err = r.ReadPacket()
+	if err != nil {
+		t.Fatal("unexpected ReadPacket error:", err)
+	}
+	p, err := r.ReadPacket()
+	if err != nil {
+		t.Fatal("unexpected ReadPacket error:", err)
+	}
+	if !bytes.Equal(p.Data, junk.Bytes()) || p.BOS || !p.EOS {
+		t.Fatalf("packet is wrong: %d bytes, BOS %v, EOS %v", len(p.Data), p.BOS, p.EOS)
+	}
+}
@@ -0,0 +1,301 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package ogg
+
+import (
+	"bytes"
+	"io"
+	"time"
+)
+
+// An Info describes a physical ogg stream, as found by Probe or Scan.
+type Info struct {
+	// Streams describes the logical streams, in the order of their BOS pages.
+	Streams []StreamInfo
+	// Duration is the longest Duration of Streams.
+	Duration time.Duration
+	// Bitrate is the average bits per second of the physical stream,
+	// or 0 if the Duration is 0.
+	Bitrate int64
+}
+
+// A StreamInfo describes a logical stream, as found by Probe or Scan.
+type StreamInfo struct {
+	Serial uint32
+	// Codec describes the stream's codec, if it's registered.
+	Codec Codec
+	// FirstGranule is the granule position of the first page with one
+	// on which a packet after the codec's header packets completes,
+	// or that of the BOS page if there's no such page.
+	FirstGranule int64
+	// LastGranule is the granule position of the last page that has one.
+	// If Probe doesn't find the stream's last page near the end of the physical stream,
+	// as in chained streams, it's that of the last page Probe read at the beginning.
+	LastGranule int64
+	// Duration is the time from the beginning of the first packet after the headers
+	// to LastGranule, or 0 if the codec's granule positions don't map to time.
+	// Where the first packet begins is estimated from the time of FirstGranule,
+	// assuming that the packets completing on its page last as long, on average,
+	// as those on the next page with a granule position.
+	Duration time.Duration
+	// Pages is the number of pages.
+	// Probe counts them by the sequence number of the last page it finds, like LastGranule.
+	Pages int64
+	// Packets is the number of packets, including the headers,
+	// and Bitrate is the average bits per second of the stream's pages,
+	// or 0 if the Duration is 0.
+	// They require reading every page, so only Scan finds them; Probe leaves them 0.
+	Packets int64
+	Bitrate int64
+}
+
+// the number of bytes that Probe reads after the BOS pages, to find where streams' data begins,
+// and before the end, to find where they end
+const probeSpan = 16 * maxPageSize
+
+// Probe finds the duration of each logical stream in the ogg stream of the given size in r,
+// reading only the first pages, for the codec headers and first granule positions,
+// and scanning backward from the end for the last pages.
+// It examines at most about a megabyte at each end.
+// The Packets and Bitrate of each StreamInfo are 0; use Scan to count them.
+//
+// For chained streams, only the streams of the first link are described.
+func Probe(r io.ReaderAt, size int64) (Info, error) {
+	var pr prober
+	d := NewDecoder(io.NewSectionReader(r, 0, size))
+	for !pr.found() {
+		p, err := d.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Info{}, err
+		}
+		if !pr.page(&p, d.Size()) || (pr.data && d.Offset() >= pr.end+probeSpan) {
+			break
+		}
+	}
+
+	err := probeBackward(r, size, d, pr.streams)
+	if err != nil {
+		return Info{}, err
+	}
+	info := pr.finish(size)
+	for i := range info.Streams {
+		info.Streams[i].Packets = 0
+	}
+	return info, nil
+}
+
+// Scan describes the ogg stream in r like Probe,
+// but reads every page, to also count the packets and bytes of each logical stream.
+//
+// For chained streams, only the streams of the first link are described.
+func Scan(r io.Reader) (Info, error) {
+	var pr prober
+	d := NewDecoder(r)
+	link := true
+	for {
+		p, err := d.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Info{}, err
+		}
+		link = link && pr.page(&p, d.Size())
+	}
+
+	info := pr.finish(d.off)
+	for i := range info.Streams {
+		s := &info.Streams[i]
+		if s.Duration > 0 {
+			s.Bitrate = int64(float64(pr.streams[i].bytes*8) / s.Duration.Seconds())
+		}
+	}
+	return info, nil
+}
+
+// A prober collects what Probe and Scan find about the streams of the first link.
+type prober struct {
+	streams []*streamProbe
+	// data is true once a page other than a BOS page has been read,
+	// and end is the offset after the BOS pages.
+	data bool
+	end  int64
+}
+
+// A streamProbe is what a prober has found about a logical stream.
+type streamProbe struct {
+	info  StreamInfo
+	seq   uint32 // the sequence number of the BOS page
+	bytes int64
+	// bos is the BOS page's granule position,
+	// and the other fields are the granule positions of the first two pages
+	// on which packets after the headers complete, and how many complete on them.
+	bos     int64
+	granule [2]int64
+	counts  [2]int64
+	n       int
+}
+
+// page adds a page to what pr has found, reporting whether it's in the first link.
+func (pr *prober) page(p *Page, size int) bool {
+	if p.Type&BOS != 0 {
+		if pr.data {
+			return false
+		}
+		codec, _ := IdentifyCodec(p.Packets[0])
+		pr.streams = append(pr.streams, &streamProbe{
+			info: StreamInfo{Serial: p.Serial, Codec: codec, FirstGranule: p.Granule, LastGranule: p.Granule},
+			seq:  p.Sequence,
+			bos:  p.Granule,
+		})
+		pr.end += int64(size)
+	} else if !pr.data {
+		pr.data = true
+	}
+
+	s := pr.stream(p.Serial)
+	if s == nil {
+		return true
+	}
+	n := int64(len(p.Packets))
+	if p.Continued {
+		n--
+	}
+	headers := int64(s.info.Codec.Headers)
+	if headers < 1 {
+		headers = 1
+	}
+	if data := s.info.Packets + n - headers; data > 0 && p.Granule != -1 && s.n < len(s.granule) {
+		if data > n {
+			data = n
+		}
+		s.granule[s.n], s.counts[s.n] = p.Granule, data
+		s.n++
+	}
+	s.info.Packets += n
+	s.info.Pages++
+	if p.Granule != -1 {
+		s.info.LastGranule = p.Granule
+	}
+	s.bytes += int64(size)
+	return true
+}
+
+func (pr *prober) stream(serial uint32) *streamProbe {
+	for _, s := range pr.streams {
+		if s.info.Serial == serial {
+			return s
+		}
+	}
+	return nil
+}
+
+// found reports whether the beginning of every stream's data has been found.
+func (pr *prober) found() bool {
+	for _, s := range pr.streams {
+		if s.n < len(s.granule) {
+			return false
+		}
+	}
+	return pr.data
+}
+
+// finish describes the streams, in a physical stream of the given size.
+func (pr *prober) finish(size int64) Info {
+	var info Info
+	for _, s := range pr.streams {
+		x := s.info
+		if s.n > 0 {
+			x.FirstGranule = s.granule[0]
+		}
+		if clock := x.Codec.Time; clock != nil {
+			begin := clock(s.bos)
+			if s.n > 1 {
+				// The first data page's packets are assumed to last as long as the next page's.
+				// This is done with times, since granule positions may not be linear.
+				t0, t1 := clock(s.granule[0]), clock(s.granule[1])
+				est := t0 - (t1-t0)*time.Duration(s.counts[0])/time.Duration(s.counts[1])
+				if est > begin {
+					begin = est
+				}
+			}
+			if begin < 0 {
+				begin = 0
+			}
+			x.Duration = clock(x.LastGranule) - begin
+		}
+		if x.Duration > info.Duration {
+			info.Duration = x.Duration
+		}
+		info.Streams = append(info.Streams, x)
+	}
+
+	if info.Duration > 0 {
+		info.Bitrate = int64(float64(size*8) / info.Duration.Seconds())
+	}
+	return info
+}
+
+// probeBackward fills in the last granule positions and page counts of streams,
+// reading chunks of r from the end until it has found all of their last pages,
+// or has read probeSpan bytes.
+// The Decoder is reused for reading pages from the chunks.
+func probeBackward(r io.ReaderAt, size int64, d *Decoder, streams []*streamProbe) error {
+	need := map[uint32]*streamProbe{}
+	counted := map[uint32]bool{}
+	for _, s := range streams {
+		need[s.info.Serial] = s
+	}
+
+	chunk := make([]byte, maxPageSize)
+	end := size
+	for end > 0 && len(need) > 0 && size-end < probeSpan {
+		begin := end - int64(len(chunk))
+		if begin < 0 {
+			begin = 0
+		}
+		buf := chunk[:end-begin]
+		_, err := r.ReadAt(buf, begin)
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		// Look at each page starting in this chunk, from last to first.
+		for i := len(buf); ; {
+			i = bytes.LastIndex(buf[:i], oggs)
+			if i < 0 {
+				break
+			}
+
+			off := begin + int64(i)
+			d.reset(io.NewSectionReader(r, off, size-off), off)
+			p, err := d.Decode()
+			if err != nil || d.Offset() != off {
+				continue
+			}
+
+			s := need[p.Serial]
+			if s == nil {
+				continue
+			}
+			if !counted[p.Serial] {
+				s.info.Pages = int64(p.Sequence-s.seq) + 1
+				counted[p.Serial] = true
+			}
+			if p.Granule != -1 {
+				s.info.LastGranule = p.Granule
+				delete(need, p.Serial)
+			}
+		}
+
+		// Overlap the chunks enough to find capture patterns that straddle them.
+		if begin == 0 {
+			break
+		}
+		end = begin + int64(len(oggs)) - 1
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package ogg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProbe(t *testing.T) {
+	vorbis := make([]byte, 30)
+	copy(vorbis, "\x01vorbis")
+	binary.LittleEndian.PutUint32(vorbis[12:], 44100)
+
+	opus := make([]byte, 19)
+	copy(opus, "OpusHead")
+	binary.LittleEndian.PutUint16(opus[10:], 312)
+
+	var b bytes.Buffer
+	m := NewMuxer(&b, PagePolicy{})
+	vstream, err := m.AddStream(1, nil)
+	if err != nil {
+		t.Fatal("unexpected AddStream error:", err)
+	}
+	ostream, err := m.AddStream(2, nil)
+	if err != nil {
+		t.Fatal("unexpected AddStream error:", err)
+	}
+	for _, h := range [][]byte{vorbis, []byte("\x03vorbis"), []byte("\x05vorbis")} {
+		err = vstream.WritePacket(0, h)
+		if err != nil {
+			t.Fatal("unexpected WritePacket error:", err)
+		}
+	}
+	for _, h := range [][]byte{opus, []byte("OpusTags")} {
+		err = ostream.WritePacket(0, h)
+		if err != nil {
+			t.Fatal("unexpected WritePacket error:", err)
+		}
+	}
+
+	packet := bytes.Repeat([]byte("data"), 100)
+	copy(packet[200:], "OggS")
+	for i := 1; i <= 10*50; i++ {
+		err = vstream.WritePacket(int64(i)*44100/50, packet)
+		if err != nil {
+			t.Fatal("unexpected WritePacket error:", err)
+		}
+		if i <= 3*50 {
+			err = ostream.WritePacket(312+int64(i)*960, packet)
+			if err != nil {
+				t.Fatal("unexpected WritePacket error:", err)
+			}
+		}
+	}
+	err = m.Close()
+	if err != nil {
+		t.Fatal("unexpected Close error:", err)
+	}
+
+	info, err := Probe(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal("unexpected Probe error:", err)
+	}
+
+	pages := map[uint32]int64{}
+	for _, p := range decodeAll(t, bytes.NewReader(b.Bytes())) {
+		pages[p.Serial]++
+	}
+
+	if len(info.Streams) != 2 {
+		t.Fatalf("expected 2 streams, got %d", len(info.Streams))
+	}
+	expect := []StreamInfo{
+		{Serial: 1, LastGranule: 441000, Duration: 10 * time.Second, Pages: pages[1]},
+		{Serial: 2, LastGranule: 312 + 144000, Duration: 3 * time.Second, Pages: pages[2]},
+	}
+	for i, x := range expect {
+		if info.Streams[i].Serial != x.Serial || info.Streams[i].LastGranule != x.LastGranule ||
+			info.Streams[i].Duration != x.Duration || info.Streams[i].Pages != x.Pages {
+			t.Fatalf("stream %d is wrong: %+v, expected %+v", i, info.Streams[i], x)
+		}
+	}
+
+	if info.Streams[0].Codec.Name != "vorbis" || info.Streams[1].Codec.Name != "opus" {
+		t.Fatalf("codecs are wrong: %q, %q", info.Streams[0].Codec.Name, info.Streams[1].Codec.Name)
+	}
+
+	if info.Duration != 10*time.Second {
+		t.Fatal("expected a 10 second duration, got", info.Duration)
+	}
+	if info.Bitrate != int64(b.Len())*8/10 {
+		t.Fatal("bitrate is wrong:", info.Bitrate)
+	}
+}
+
+func TestProbeLateStart(t *testing.T) {
+	// An opus stream cut from a broadcast 5 seconds in.
+	opus := make([]byte, 19)
+	copy(opus, "OpusHead")
+	binary.LittleEndian.PutUint16(opus[10:], 312)
+	start := int64(312 + 5*48000)
+
+	var b bytes.Buffer
+	w := NewPacketWriter(1, &b, PagePolicy{})
+	for _, h := range [][]byte{opus, []byte("OpusTags")} {
+		err := w.WritePacket(0, h)
+		if err != nil {
+			t.Fatal("unexpected WritePacket error:", err)
+		}
+	}
+	packet := bytes.Repeat([]byte("data"), 100)
+	for i := 1; i <= 50; i++ {
+		err := w.WritePacket(start+int64(i)*960, packet)
+		if err != nil {
+			t.Fatal("unexpected WritePacket error:", err)
+		}
+	}
+	err := w.Close()
+	if err != nil {
+		t.Fatal("unexpected Close error:", err)
+	}
+
+	pages := decodeAll(t, bytes.NewReader(b.Bytes()))
+	first := int64(-1)
+	for _, p := range pages {
+		if p.Granule > 0 {
+			first = p.Granule
+			break
+		}
+	}
+
+	info, err := Probe(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal("unexpected Probe error:", err)
+	}
+	expect := StreamInfo{
+		Serial:       1,
+		FirstGranule: first,
+		LastGranule:  start + 48000,
+		Duration:     time.Second,
+		Pages:        int64(len(pages)),
+	}
+	if len(info.Streams) != 1 || info.Streams[0].Codec.Name != "opus" {
+		t.Fatalf("expected 1 opus stream, got %+v", info.Streams)
+	}
+	got := info.Streams[0]
+	got.Codec = Codec{}
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatalf("expected %+v, got %+v", expect, got)
+	}
+	if info.Duration != time.Second {
+		t.Fatal("expected a 1 second duration, got", info.Duration)
+	}
+
+	info, err = Scan(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatal("unexpected Scan error:", err)
+	}
+	expect.Packets = 2 + 50
+	expect.Bitrate = int64(b.Len()) * 8
+	if len(info.Streams) != 1 || info.Streams[0].Codec.Name != "opus" {
+		t.Fatalf("expected 1 opus stream, got %+v", info.Streams)
+	}
+	got = info.Streams[0]
+	got.Codec = Codec{}
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatalf("expected %+v, got %+v", expect, got)
+	}
+	if info.Duration != time.Second || info.Bitrate != int64(b.Len())*8 {
+		t.Fatalf("expected a 1 second duration at %d bps, got %v at %d", b.Len()*8, info.Duration, info.Bitrate)
+	}
+}
+
+func TestProbeShiftedGranules(t *testing.T) {
+	// A Theora 3.2.1 stream at 25 frames per second with a granule shift of 6,
+	// starting at a keyframe at frame 30.
+	theora := make([]byte, 42)
+	copy(theora, "\x80theora\x03\x02\x01")
+	binary.BigEndian.PutUint32(theora[22:], 25)
+	binary.BigEndian.PutUint32(theora[26:], 1)
+	theora[41] = 6 << 5
+
+	var b bytes.Buffer
+	w := NewPacketWriter(1, &b, PagePolicy{MaxPackets: 1})
+	for _, h := range [][]byte{theora, []byte("\x81theora"), []byte("\x82theora")} {
+		err := w.WritePacket(0, h)
+		if err != nil {
+			t.Fatal("unexpected WritePacket error:", err)
+		}
+	}
+	for i := int64(0); i < 50; i++ {
+		err := w.WritePacket(30<<6|i, []byte("frame"))
+		if err != nil {
+			t.Fatal("unexpected WritePacket error:", err)
+		}
+	}
+	err := w.Close()
+	if err != nil {
+		t.Fatal("unexpected Close error:", err)
+	}
+
+	// Frames 30 to 79 end at 1.2 and 3.16 seconds, so frame 30 begins at 1.16.
+	info, err := Probe(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal("unexpected Probe error:", err)
+	}
+	if len(info.Streams) != 1 || info.Streams[0].FirstGranule != 30<<6 || info.Streams[0].Duration != 2*time.Second {
+		t.Fatalf("expected 2 seconds from granule %d, got %+v", 30<<6, info.Streams)
+	}
+}
+
+// countingReaderAt counts the bytes read from r.
+type countingReaderAt struct {
+	r *bytes.Reader
+	n int64
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := c.r.ReadAt(p, off)
+	c.n += int64(n)
+	return n, err
+}
+
+func TestProbeChain(t *testing.T) {
+	vorbis := make([]byte, 30)
+	copy(vorbis, "\x01vorbis")
+	binary.LittleEndian.PutUint32(vorbis[12:], 44100)
+
+	// A short link, and a long one whose streams have other serials.
+	var b bytes.Buffer
+	links := []struct {
+		serial  uint32
+		packets int
+	}{
+		{1, 10},
+		{2, 10000},
+	}
+	for _, l := range links {
+		w := NewPacketWriter(l.serial, &b, PagePolicy{})
+		for _, h := range [][]byte{vorbis, []byte("\x03vorbis"), []byte("\x05vorbis")} {
+			err := w.WritePacket(0, h)
+			if err != nil {
+				t.Fatal("unexpected WritePacket error:", err)
+			}
+		}
+		for i := 1; i < l.packets; i++ {
+			err := w.WritePacket(int64(i)*441, make([]byte, 400))
+			if err != nil {
+				t.Fatal("unexpected WritePacket error:", err)
+			}
+		}
+		err := w.WriteLastPacket(int64(l.packets)*441, make([]byte, 400))
+		if err != nil {
+			t.Fatal("unexpected WriteLastPacket error:", err)
+		}
+	}
+
+	// Probe doesn't read the whole second link looking for the first's last page.
+	r := &countingReaderAt{r: bytes.NewReader(b.Bytes())}
+	info, err := Probe(r, int64(b.Len()))
+	if err != nil {
+		t.Fatal("unexpected Probe error:", err)
+	}
+	if r.n > 3*probeSpan || r.n >= int64(b.Len()) {
+		t.Fatalf("read %d bytes of %d", r.n, b.Len())
+	}
+	if len(info.Streams) != 1 || info.Streams[0].Serial != 1 || info.Streams[0].LastGranule != 10*441 {
+		t.Fatalf("streams are wrong: %+v", info.Streams)
+	}
+}
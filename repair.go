@@ -0,0 +1,229 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package ogg
+
+import (
+	"io"
+)
+
+// A RepairReport describes what Repair changed.
+type RepairReport struct {
+	// Damage is the damaged regions of the input that were skipped.
+	Damage []Damage
+	// Dropped is the number of valid pages left out,
+	// because they follow their stream's EOS page, belong to no stream begun by a BOS page,
+	// or hold only parts of packets that were lost.
+	Dropped int
+	// Trimmed is the number of packets removed because parts of them were lost.
+	Trimmed int
+	// Renumbered is the number of pages given new sequence numbers.
+	Renumbered int
+	// Ended is the serial numbers of the streams that were given EOS pages.
+	Ended []uint32
+}
+
+// Repair copies the ogg stream in src to dst, fixing what it can of the damage in it.
+// It reads src like a lenient Decoder, skipping invalid pages,
+// and then removes what's left of the packets that spanned them.
+// The pages of each logical stream are renumbered in sequence with new CRCs,
+// and streams that don't end with an EOS page are given one,
+// with an empty packet at the last granule position.
+// A BOS page after other pages starts a new link of a chained stream,
+// ending the streams of the previous one.
+//
+// Only page framing is repaired; the packets are copied without regard to their codecs,
+// and granule positions are left as they are.
+// Pages that end with part of a packet are held in memory until the packet is complete,
+// so the pages of different streams may be interleaved slightly differently.
+func Repair(dst io.Writer, src io.Reader) (RepairReport, error) {
+	var rep RepairReport
+	rp := repairer{w: dst, rep: &rep, streams: map[uint32]*repairStream{}}
+	d := NewLenientDecoder(src, func(dmg Damage) {
+		rep.Damage = append(rep.Damage, dmg)
+	})
+	for {
+		p, err := d.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return rep, err
+		}
+		err = rp.page(p)
+		if err != nil {
+			return rep, err
+		}
+	}
+	return rep, rp.end()
+}
+
+type repairer struct {
+	w       io.Writer
+	rep     *RepairReport
+	streams map[uint32]*repairStream
+	// serials are the streams of the current link, in the order of their BOS pages.
+	serials []uint32
+	// data is true once a page other than a BOS page is in the current link.
+	data bool
+}
+
+// A repairStream is the state of a logical stream being repaired.
+type repairStream struct {
+	enc *Encoder
+	// seq is the input sequence number of the last page.
+	seq     uint32
+	granule int64
+	// held are pages whose last packet isn't complete yet.
+	held []Page
+	// lost is true if the parts of a lost packet are being dropped.
+	lost bool
+	eos  bool
+}
+
+func (rp *repairer) page(p Page) error {
+	p.Type &= COP | BOS | EOS
+	s := rp.streams[p.Serial]
+	if p.Type&BOS != 0 {
+		// A BOS page can't continue a packet.
+		p.Type &^= COP
+		if rp.data {
+			err := rp.end()
+			if err != nil {
+				return err
+			}
+			s = nil
+		}
+		if s != nil {
+			rp.rep.Dropped++
+			return nil
+		}
+		s = &repairStream{enc: NewEncoder(p.Serial, rp.w), seq: p.Sequence - 1, granule: -1}
+		rp.streams[p.Serial] = s
+		rp.serials = append(rp.serials, p.Serial)
+	} else {
+		rp.data = true
+		if s == nil || s.eos {
+			rp.rep.Dropped++
+			return nil
+		}
+	}
+
+	gap := p.Sequence != s.seq+1
+	s.seq = p.Sequence
+	if len(s.held) > 0 && (gap || p.Type&COP == 0) {
+		err := rp.trim(s)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Drop the rest of a packet whose beginning was lost.
+	if p.Type&COP != 0 && len(s.held) == 0 {
+		if !s.lost {
+			rp.rep.Trimmed++
+		}
+		p.Type &^= COP
+		p.Packets = p.Packets[1:]
+		s.lost = len(p.Packets) == 0
+		if s.lost {
+			rp.rep.Dropped++
+			return nil
+		}
+	}
+	s.lost = false
+
+	if p.Continued && p.Type&EOS != 0 {
+		p.Packets = p.Packets[:len(p.Packets)-1]
+		p.Continued = false
+		rp.rep.Trimmed++
+		if len(p.Packets) == 0 {
+			p.Packets = [][]byte{nil}
+		}
+	}
+
+	if p.Continued {
+		s.held = append(s.held, copyPage(p))
+		return nil
+	}
+	for _, h := range s.held {
+		err := rp.write(s, h)
+		if err != nil {
+			return err
+		}
+	}
+	s.held = nil
+	return rp.write(s, p)
+}
+
+// trim writes a stream's held pages without the packet they don't complete.
+func (rp *repairer) trim(s *repairStream) error {
+	rp.rep.Trimmed++
+	rp.rep.Dropped += len(s.held) - 1
+	p := s.held[0]
+	s.held = nil
+	p.Packets = p.Packets[:len(p.Packets)-1]
+	p.Continued = false
+	if len(p.Packets) == 0 {
+		if p.Type&BOS == 0 {
+			rp.rep.Dropped++
+			return nil
+		}
+		p.Packets = [][]byte{nil}
+	}
+	return rp.write(s, p)
+}
+
+func (rp *repairer) write(s *repairStream, p Page) error {
+	// Trimming may leave a page on which no packet ends.
+	if p.Continued && len(p.Packets) == 1 {
+		p.Granule = -1
+	}
+	if p.Sequence != s.enc.page {
+		rp.rep.Renumbered++
+	}
+	if p.Granule != -1 {
+		s.granule = p.Granule
+	}
+	s.eos = p.Type&EOS != 0
+	return s.enc.WritePage(p)
+}
+
+// end finishes the streams of the current link.
+func (rp *repairer) end() error {
+	for _, serial := range rp.serials {
+		s := rp.streams[serial]
+		if len(s.held) > 0 {
+			err := rp.trim(s)
+			if err != nil {
+				return err
+			}
+		}
+		if s.eos {
+			continue
+		}
+		rp.rep.Ended = append(rp.rep.Ended, serial)
+		if s.granule < 0 {
+			s.granule = 0
+		}
+		err := s.enc.EncodeEOS(s.granule, nil)
+		if err != nil {
+			return err
+		}
+	}
+	rp.streams = map[uint32]*repairStream{}
+	rp.serials = nil
+	rp.data = false
+	return nil
+}
+
+// copyPage returns a copy of p whose Packets don't share memory with p's.
+// The copy has no Segments.
+func copyPage(p Page) Page {
+	packets := make([][]byte, len(p.Packets))
+	for i, packet := range p.Packets {
+		packets[i] = append([]byte(nil), packet...)
+	}
+	p.Packets = packets
+	p.Segments = nil
+	return p
+}
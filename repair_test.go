@@ -0,0 +1,166 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package ogg
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// pageRecorder keeps each Write, which is one page from an Encoder, separately.
+type pageRecorder [][]byte
+
+func (r *pageRecorder) Write(b []byte) (int, error) {
+	*r = append(*r, append([]byte(nil), b...))
+	return len(b), nil
+}
+
+func TestRepair(t *testing.T) {
+	var pages pageRecorder
+	a, b := NewEncoder(1, &pages), NewEncoder(2, &pages)
+	big := bytes.Repeat([]byte("big"), 30000)
+	steps := []func() error{
+		func() error { return a.EncodeBOS(0, [][]byte{[]byte("a head")}) },
+		func() error { return b.EncodeBOS(0, [][]byte{[]byte("b head")}) },
+		func() error { return a.Encode(10, [][]byte{[]byte("a1")}) },
+		func() error { return a.Encode(20, [][]byte{big}) },
+		func() error { return b.Encode(5, [][]byte{[]byte("b1")}) },
+		func() error { return b.Encode(6, [][]byte{[]byte("b2")}) },
+		func() error { return b.EncodeEOS(7, [][]byte{[]byte("b3")}) },
+		func() error { return a.Encode(30, [][]byte{[]byte("a3"), []byte("a4")}) },
+	}
+	for _, f := range steps {
+		err := f()
+		if err != nil {
+			t.Fatal("unexpected error building the stream:", err)
+		}
+	}
+	if len(pages) != 9 {
+		t.Fatal("expected 9 pages, got", len(pages))
+	}
+
+	// Damage the second page of the big packet, lose the page of b2,
+	// put junk between pages, and lose a's EOS page.
+	pages[4][100]++
+	var src []byte
+	for i, p := range pages {
+		if i == 6 {
+			continue
+		}
+		if i == 7 {
+			src = append(src, "junk"...)
+		}
+		src = append(src, p...)
+	}
+
+	var dst bytes.Buffer
+	rep, err := Repair(&dst, bytes.NewReader(src))
+	if err != nil {
+		t.Fatal("unexpected Repair error:", err)
+	}
+	if len(rep.Damage) != 2 || rep.Damage[0].Serial != 1 || rep.Damage[1].Err != nil ||
+		rep.Dropped != 1 || rep.Trimmed != 1 || rep.Renumbered != 2 || !reflect.DeepEqual(rep.Ended, []uint32{1}) {
+		t.Fatalf("report is wrong: %+v", rep)
+	}
+
+	findings, err := Validate(bytes.NewReader(dst.Bytes()))
+	if err != nil {
+		t.Fatal("unexpected Validate error:", err)
+	}
+	if len(findings) != 0 {
+		t.Fatal("the repaired stream is invalid:", findings)
+	}
+
+	packets := map[uint32][]string{}
+	r := NewPacketReader(bytes.NewReader(dst.Bytes()))
+	for {
+		p, err := r.ReadPacket()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal("unexpected ReadPacket error:", err)
+		}
+		packets[p.Serial] = append(packets[p.Serial], string(p.Data))
+	}
+	expect := map[uint32][]string{
+		1: {"a head", "a1", "a3", "a4", ""},
+		2: {"b head", "b1", "b3"},
+	}
+	if !reflect.DeepEqual(packets, expect) {
+		t.Fatalf("expected packets %q, got %q", expect, packets)
+	}
+}
+
+func TestRepairChain(t *testing.T) {
+	var b bytes.Buffer
+	e := NewEncoder(1, &b)
+	err := e.EncodeBOS(0, nil)
+	if err != nil {
+		t.Fatal("unexpected EncodeBOS error:", err)
+	}
+	err = e.Encode(10, [][]byte{[]byte("truncated link")})
+	if err != nil {
+		t.Fatal("unexpected Encode error:", err)
+	}
+	e = NewEncoder(1, &b)
+	err = e.EncodeBOS(0, nil)
+	if err != nil {
+		t.Fatal("unexpected EncodeBOS error:", err)
+	}
+	err = e.EncodeEOS(20, nil)
+	if err != nil {
+		t.Fatal("unexpected EncodeEOS error:", err)
+	}
+
+	var dst bytes.Buffer
+	rep, err := Repair(&dst, &b)
+	if err != nil {
+		t.Fatal("unexpected Repair error:", err)
+	}
+	if !reflect.DeepEqual(rep.Ended, []uint32{1}) {
+		t.Fatal("expected the first link to be ended, got", rep.Ended)
+	}
+	findings, err := Validate(&dst)
+	if err != nil {
+		t.Fatal("unexpected Validate error:", err)
+	}
+	if len(findings) != 0 {
+		t.Fatal("the repaired stream is invalid:", findings)
+	}
+}
+
+func TestRepairLostStart(t *testing.T) {
+	long := bytes.Repeat([]byte("q"), 255)
+	parts := [][]byte{
+		testPage(t, 1, 0, Page{Type: BOS, Packets: [][]byte{[]byte("head")}}, nil),
+		testPage(t, 1, 1, Page{Granule: 10, Packets: [][]byte{[]byte("p1"), long}, Continued: true}, nil),
+		// Once p2's beginning is lost, no packet ends on this page.
+		testPage(t, 1, 2, Page{Type: COP, Granule: 20, Packets: [][]byte{[]byte("p2"), long}, Continued: true}, nil),
+		testPage(t, 1, 3, Page{Type: COP | EOS, Granule: 30, Packets: [][]byte{[]byte("p3")}}, nil),
+	}
+	src := append(append(append([]byte(nil), parts[0]...), parts[2]...), parts[3]...)
+
+	var dst bytes.Buffer
+	rep, err := Repair(&dst, bytes.NewReader(src))
+	if err != nil {
+		t.Fatal("unexpected Repair error:", err)
+	}
+	if rep.Trimmed != 1 || rep.Renumbered != 2 {
+		t.Fatalf("report is wrong: %+v", rep)
+	}
+
+	findings, err := Validate(bytes.NewReader(dst.Bytes()))
+	if err != nil {
+		t.Fatal("unexpected Validate error:", err)
+	}
+	if len(findings) != 0 {
+		t.Fatal("the repaired stream is invalid:", findings)
+	}
+	pages := decodeAll(t, &dst)
+	if len(pages) != 3 || pages[1].Granule != -1 || pages[2].Granule != 30 {
+		t.Fatalf("pages are wrong: %+v", pages)
+	}
+}
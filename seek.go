@@ -0,0 +1,181 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package ogg
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrSeekRange is the error used when seeking to a granule position
+// that is beyond the end of a logical stream.
+var ErrSeekRange = errors.New("granule position out of range")
+
+// errNoPage is used internally when a region of the stream has no suitable page.
+var errNoPage = errors.New("no page found")
+
+// the size of the region in which a Seeker stops bisecting and scans forward
+const seekSpan = 2 * maxPageSize
+
+// A Seeker finds the pages of a logical stream by granule position,
+// bisecting the physical stream rather than reading all of it,
+// like libvorbisfile's ov_pcm_seek.
+type Seeker struct {
+	r    io.ReadSeeker
+	size int64
+	d    *Decoder // reused for each position examined
+}
+
+// NewSeeker creates a Seeker for the ogg stream in r.
+// The stream is assumed to begin at offset 0 and to end where r ends.
+func NewSeeker(r io.ReadSeeker) (*Seeker, error) {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	return &Seeker{r: r, size: size, d: new(Decoder)}, nil
+}
+
+// SeekGranule finds the page of the logical stream with the given serial
+// that completes the first packet with a granule position at or after the given one,
+// and returns a PacketReader positioned so that the first packet it returns
+// from that logical stream is that packet.
+// The PacketReader also returns packets from any other logical streams in the physical stream
+// from that position on.
+//
+// Granule positions are assumed to increase throughout the logical stream,
+// and pages that fail their CRC check are skipped.
+// If every page of the logical stream has a smaller granule position,
+// the error is ErrSeekRange.
+func (s *Seeker) SeekGranule(serial uint32, granule int64) (*PacketReader, error) {
+	lo, hi := int64(0), s.size
+	start := int64(0)
+	for hi-lo > seekSpan {
+		mid := lo + (hi-lo)/2
+		off, g, err := s.pageAfter(mid, hi, serial)
+		if err == errNoPage {
+			hi = mid
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if g < granule {
+			lo, start = off, off
+		} else {
+			hi = mid
+		}
+	}
+
+	// Find the last page before the target, since the target's packet may begin on it.
+	d, err := s.decoderAt(start)
+	if err != nil {
+		return nil, err
+	}
+	begin := start
+	skip := 0
+	for {
+		p, err := d.Decode()
+		if isDamage(err) {
+			d, err = s.decoderAt(d.start + 1)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrSeekRange
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if p.Serial != serial || p.Granule == -1 {
+			continue
+		}
+		if p.Granule >= granule {
+			break
+		}
+
+		// Start after this page, unless the target packet begins on it,
+		// in which case the packets that complete on it are skipped.
+		begin = d.Offset()
+		skip = 0
+		if p.Continued {
+			skip = len(p.Packets) - 1
+			if p.Type&COP != 0 {
+				// The PacketReader drops the continuation, since it didn't see the packet begin.
+				skip--
+			}
+		} else {
+			begin += int64(d.Size())
+		}
+	}
+
+	_, err = s.r.Seek(begin, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+	d = new(Decoder)
+	d.reset(s.r, begin)
+	r := newPacketReader(d)
+	r.skipSerial, r.skip = serial, skip
+	return r, nil
+}
+
+// pageAfter finds the first page of the logical stream with a granule position
+// that starts in the region [from, to) and returns its offset and granule position.
+func (s *Seeker) pageAfter(from, to int64, serial uint32) (int64, int64, error) {
+	d, err := s.decoderAt(from)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for {
+		p, err := d.Decode()
+		if isDamage(err) {
+			if d.start >= to {
+				return 0, 0, errNoPage
+			}
+			d, err = s.decoderAt(d.start + 1)
+			if err != nil {
+				return 0, 0, err
+			}
+			continue
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return 0, 0, errNoPage
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+
+		if d.Offset() >= to {
+			return 0, 0, errNoPage
+		}
+		if p.Serial == serial && p.Granule != -1 {
+			return d.Offset(), p.Granule, nil
+		}
+	}
+}
+
+// decoderAt returns the Seeker's Decoder, reading from the given offset.
+func (s *Seeker) decoderAt(off int64) (*Decoder, error) {
+	_, err := s.r.Seek(off, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+	s.d.reset(s.r, off)
+	return s.d, nil
+}
+
+// isDamage reports whether err means the Decoder found
+// a capture pattern that didn't begin a valid page,
+// which happens when seeking lands in the middle of a page.
+func isDamage(err error) bool {
+	if _, ok := err.(ErrBadCrc); ok {
+		return true
+	}
+	return err == ErrBadSegs
+}
@@ -0,0 +1,123 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package ogg
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestSeekGranule(t *testing.T) {
+	var b bytes.Buffer
+	audio := NewPacketWriter(1, &b, PagePolicy{MaxPackets: 1})
+	other := NewEncoder(2, &b)
+
+	err := audio.WritePacket(0, []byte("head"))
+	if err != nil {
+		t.Fatal("unexpected WritePacket error:", err)
+	}
+	err = other.EncodeBOS(0, nil)
+	if err != nil {
+		t.Fatal("unexpected EncodeBOS error:", err)
+	}
+
+	// Packets are full of capture patterns, to trip up resynchronization,
+	// and some span pages.
+	const n = 200
+	for i := 1; i <= n; i++ {
+		size := 1000 + rand.Intn(4000)
+		if i%10 == 0 {
+			size = maxPageSize + 1000
+		}
+		packet := bytes.Repeat([]byte("OggS"), size/4)
+		packet[0] = byte(i)
+		packet[1] = byte(i >> 8)
+
+		err = audio.WritePacket(int64(i*100), packet)
+		if err != nil {
+			t.Fatal("unexpected WritePacket error:", err)
+		}
+		err = other.Encode(int64(i), [][]byte{packet[:100]})
+		if err != nil {
+			t.Fatal("unexpected Encode error:", err)
+		}
+	}
+	err = audio.Close()
+	if err != nil {
+		t.Fatal("unexpected Close error:", err)
+	}
+
+	s, err := NewSeeker(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatal("unexpected NewSeeker error:", err)
+	}
+
+	for _, target := range []int64{0, 1, 550, 900, 999, 1000, 1001, 5000, 12345, n * 100} {
+		r, err := s.SeekGranule(1, target)
+		if err != nil {
+			t.Fatalf("unexpected SeekGranule(%d) error: %v", target, err)
+		}
+
+		for {
+			p, err := r.ReadPacket()
+			if err != nil {
+				t.Fatalf("unexpected ReadPacket error after SeekGranule(%d): %v", target, err)
+			}
+			if p.Serial != 1 {
+				continue
+			}
+
+			expect := (target + 99) / 100 * 100
+			if p.Granule != expect {
+				t.Fatalf("SeekGranule(%d) found granule %d, expected %d", target, p.Granule, expect)
+			}
+			if expect > 0 && (int64(p.Data[0])|int64(p.Data[1])<<8) != expect/100 {
+				t.Fatalf("SeekGranule(%d) found the wrong packet: %d", target, p.Data[0])
+			}
+			break
+		}
+	}
+
+	_, err = s.SeekGranule(1, n*100+1)
+	if err != ErrSeekRange {
+		t.Fatal("expected ErrSeekRange, got:", err)
+	}
+}
+
+func TestSeekGranuleContinued(t *testing.T) {
+	var b bytes.Buffer
+	e := NewEncoder(1, &b)
+	long := bytes.Repeat([]byte("x"), mss)
+
+	// The target packet begins on a page that completes another packet.
+	pages := []Page{
+		{Type: BOS, Granule: 0, Packets: [][]byte{[]byte("head")}},
+		{Granule: 100, Packets: [][]byte{[]byte("before"), long}, Continued: true},
+		{Type: COP, Granule: 300, Packets: [][]byte{[]byte("target"), []byte("after")}},
+	}
+	for _, p := range pages {
+		err := e.WritePage(p)
+		if err != nil {
+			t.Fatal("unexpected WritePage error:", err)
+		}
+	}
+
+	s, err := NewSeeker(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatal("unexpected NewSeeker error:", err)
+	}
+	r, err := s.SeekGranule(1, 150)
+	if err != nil {
+		t.Fatal("unexpected SeekGranule error:", err)
+	}
+	for _, x := range []string{string(long) + "target", "after"} {
+		p, err := r.ReadPacket()
+		if err != nil {
+			t.Fatal("unexpected ReadPacket error:", err)
+		}
+		if string(p.Data) != x {
+			t.Fatalf("expected %.10q, got %.10q", x, p.Data)
+		}
+	}
+}
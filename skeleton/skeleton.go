@@ -0,0 +1,463 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+/*
+Package skeleton implements Ogg Skeleton 4.0 as defined in
+https://wiki.xiph.org/SkeletonHeaders :
+the fishead, fisbone, and index packets,
+and reading and writing Skeleton tracks alongside other logical streams.
+*/
+package skeleton
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+
+	"mccoy.space/g/ogg"
+)
+
+var (
+	headMagic  = []byte("fishead\x00")
+	boneMagic  = []byte("fisbone\x00")
+	indexMagic = []byte("index\x00")
+)
+
+// The version of Skeleton written by this package.
+const (
+	VersionMajor = 4
+	VersionMinor = 0
+)
+
+const (
+	headSize    = 80
+	headSizeV3  = 64
+	boneFields  = 52
+	indexFields = 42
+)
+
+// ErrMagic is the error used when a packet lacks its magic signature.
+var ErrMagic = errors.New("missing Skeleton packet signature")
+
+// ErrShort is the error used when a packet is truncated.
+var ErrShort = errors.New("truncated Skeleton packet")
+
+// ErrVersion is the error used for an unsupported Skeleton version.
+var ErrVersion = errors.New("unsupported Skeleton version")
+
+// ErrMessageHeader is the error used for a malformed fisbone message header.
+var ErrMessageHeader = errors.New("invalid Skeleton message header")
+
+// ErrKeypoints is the error used for an index whose keypoints aren't in order.
+var ErrKeypoints = errors.New("Skeleton index keypoints out of order")
+
+// A Head is the fishead packet, which begins a Skeleton track.
+type Head struct {
+	VersionMajor uint16
+	VersionMinor uint16
+	// The presentation time is the time at which to begin playback.
+	PresentationTimeNumerator   int64
+	PresentationTimeDenominator int64
+	// The basetime is the time of granule position 0 in all streams.
+	BasetimeNumerator   int64
+	BasetimeDenominator int64
+	// UTC is the wall-clock time of the basetime, as in ISO 8601, or zero.
+	UTC [20]byte
+	// SegmentLength is the length of the physical stream in bytes,
+	// and ContentOffset is the offset of the first non-header page.
+	// They're only in version 4, and are 0 if unknown.
+	SegmentLength uint64
+	ContentOffset uint64
+}
+
+// MarshalBinary encodes h as a fishead packet of version 4.
+func (h *Head) MarshalBinary() ([]byte, error) {
+	if h.VersionMajor != VersionMajor {
+		return nil, ErrVersion
+	}
+	b := make([]byte, headSize)
+	copy(b, headMagic)
+	binary.LittleEndian.PutUint16(b[8:], h.VersionMajor)
+	binary.LittleEndian.PutUint16(b[10:], h.VersionMinor)
+	binary.LittleEndian.PutUint64(b[12:], uint64(h.PresentationTimeNumerator))
+	binary.LittleEndian.PutUint64(b[20:], uint64(h.PresentationTimeDenominator))
+	binary.LittleEndian.PutUint64(b[28:], uint64(h.BasetimeNumerator))
+	binary.LittleEndian.PutUint64(b[36:], uint64(h.BasetimeDenominator))
+	copy(b[44:64], h.UTC[:])
+	binary.LittleEndian.PutUint64(b[64:], h.SegmentLength)
+	binary.LittleEndian.PutUint64(b[72:], h.ContentOffset)
+	return b, nil
+}
+
+// UnmarshalBinary decodes a fishead packet of version 3 or 4 into h.
+func (h *Head) UnmarshalBinary(b []byte) error {
+	if !bytes.HasPrefix(b, headMagic) {
+		return ErrMagic
+	}
+	if len(b) < headSizeV3 {
+		return ErrShort
+	}
+
+	*h = Head{
+		VersionMajor:                binary.LittleEndian.Uint16(b[8:]),
+		VersionMinor:                binary.LittleEndian.Uint16(b[10:]),
+		PresentationTimeNumerator:   int64(binary.LittleEndian.Uint64(b[12:])),
+		PresentationTimeDenominator: int64(binary.LittleEndian.Uint64(b[20:])),
+		BasetimeNumerator:           int64(binary.LittleEndian.Uint64(b[28:])),
+		BasetimeDenominator:         int64(binary.LittleEndian.Uint64(b[36:])),
+	}
+	copy(h.UTC[:], b[44:64])
+
+	switch h.VersionMajor {
+	case 3:
+	case 4:
+		if len(b) < headSize {
+			return ErrShort
+		}
+		h.SegmentLength = binary.LittleEndian.Uint64(b[64:])
+		h.ContentOffset = binary.LittleEndian.Uint64(b[72:])
+	default:
+		return ErrVersion
+	}
+	return nil
+}
+
+// A Field is a message header field of a fisbone, like "Content-Type: audio/vorbis".
+type Field struct {
+	Name  string
+	Value string
+}
+
+// A Bone is a fisbone packet, which describes another logical stream.
+type Bone struct {
+	// Serial is the serial number of the described stream.
+	Serial uint32
+	// HeaderPackets is the number of header packets in the stream.
+	HeaderPackets uint32
+	// The granule rate is the number of granules per second.
+	GranuleRateNumerator   int64
+	GranuleRateDenominator int64
+	// BaseGranule is the granule position corresponding to the basetime.
+	BaseGranule int64
+	// Preroll is the number of packets to decode before one at a seek target.
+	Preroll uint32
+	// GranuleShift is the number of low bits of granule positions that are
+	// an offset from a keyframe, as in Theora, or 0.
+	GranuleShift uint8
+	// Fields are the message header fields,
+	// which must begin with Content-Type.
+	Fields []Field
+}
+
+// Get returns the value of the first message header field with the given name,
+// which is case-insensitive, or "" if there isn't one.
+func (b *Bone) Get(name string) string {
+	for _, f := range b.Fields {
+		if strings.EqualFold(f.Name, name) {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// Set sets the value of the message header field with the given name,
+// replacing any existing fields with that name.
+// A new Content-Type goes first.
+func (b *Bone) Set(name, value string) {
+	kept := b.Fields[:0]
+	for _, f := range b.Fields {
+		if !strings.EqualFold(f.Name, name) {
+			kept = append(kept, f)
+		}
+	}
+	b.Fields = kept
+
+	if strings.EqualFold(name, "Content-Type") {
+		b.Fields = append([]Field{{name, value}}, b.Fields...)
+	} else {
+		b.Fields = append(b.Fields, Field{name, value})
+	}
+}
+
+// MarshalBinary encodes b as a fisbone packet.
+func (b *Bone) MarshalBinary() ([]byte, error) {
+	p := make([]byte, boneFields)
+	copy(p, boneMagic)
+	binary.LittleEndian.PutUint32(p[8:], boneFields-8)
+	binary.LittleEndian.PutUint32(p[12:], b.Serial)
+	binary.LittleEndian.PutUint32(p[16:], b.HeaderPackets)
+	binary.LittleEndian.PutUint64(p[20:], uint64(b.GranuleRateNumerator))
+	binary.LittleEndian.PutUint64(p[28:], uint64(b.GranuleRateDenominator))
+	binary.LittleEndian.PutUint64(p[36:], uint64(b.BaseGranule))
+	binary.LittleEndian.PutUint32(p[44:], b.Preroll)
+	p[48] = b.GranuleShift
+
+	for _, f := range b.Fields {
+		if f.Name == "" || strings.ContainsAny(f.Name, ":\r\n") || strings.ContainsAny(f.Value, "\r\n") {
+			return nil, ErrMessageHeader
+		}
+		p = append(p, f.Name...)
+		p = append(p, ": "...)
+		p = append(p, f.Value...)
+		p = append(p, "\r\n"...)
+	}
+	return p, nil
+}
+
+// UnmarshalBinary decodes a fisbone packet into b.
+func (b *Bone) UnmarshalBinary(p []byte) error {
+	if !bytes.HasPrefix(p, boneMagic) {
+		return ErrMagic
+	}
+	if len(p) < boneFields {
+		return ErrShort
+	}
+	off := uint64(binary.LittleEndian.Uint32(p[8:])) + 8
+	if off < boneFields || off > uint64(len(p)) {
+		return ErrShort
+	}
+
+	*b = Bone{
+		Serial:                 binary.LittleEndian.Uint32(p[12:]),
+		HeaderPackets:          binary.LittleEndian.Uint32(p[16:]),
+		GranuleRateNumerator:   int64(binary.LittleEndian.Uint64(p[20:])),
+		GranuleRateDenominator: int64(binary.LittleEndian.Uint64(p[28:])),
+		BaseGranule:            int64(binary.LittleEndian.Uint64(p[36:])),
+		Preroll:                binary.LittleEndian.Uint32(p[44:]),
+		GranuleShift:           p[48],
+	}
+
+	for _, line := range strings.SplitAfter(string(p[off:]), "\r\n") {
+		if line == "" {
+			continue
+		}
+		if !strings.HasSuffix(line, "\r\n") {
+			return ErrMessageHeader
+		}
+		name, value, ok := strings.Cut(strings.TrimSuffix(line, "\r\n"), ":")
+		if !ok || name == "" {
+			return ErrMessageHeader
+		}
+		b.Fields = append(b.Fields, Field{name, strings.TrimLeft(value, " \t")})
+	}
+	return nil
+}
+
+// A Keypoint is a point in an Index.
+type Keypoint struct {
+	// Offset is the byte offset of the page on which to start decoding.
+	Offset int64
+	// Time is the numerator of the time of the first sample that can be decoded from Offset.
+	Time int64
+}
+
+// An Index is an index packet, which lists keypoints of another logical stream for seeking.
+type Index struct {
+	// Serial is the serial number of the indexed stream.
+	Serial uint32
+	// Denominator is the denominator of the index's times.
+	Denominator int64
+	// FirstTime is the numerator of the time of the stream's first sample,
+	// and LastTime that of the end of its last sample.
+	FirstTime int64
+	LastTime  int64
+	// Keypoints are in nondecreasing order of both Offset and Time.
+	Keypoints []Keypoint
+}
+
+// MarshalBinary encodes x as an index packet.
+func (x *Index) MarshalBinary() ([]byte, error) {
+	b := make([]byte, indexFields, indexFields+len(x.Keypoints)*4)
+	copy(b, indexMagic)
+	binary.LittleEndian.PutUint32(b[6:], x.Serial)
+	binary.LittleEndian.PutUint64(b[10:], uint64(len(x.Keypoints)))
+	binary.LittleEndian.PutUint64(b[18:], uint64(x.Denominator))
+	binary.LittleEndian.PutUint64(b[26:], uint64(x.FirstTime))
+	binary.LittleEndian.PutUint64(b[34:], uint64(x.LastTime))
+
+	var prev Keypoint
+	for _, k := range x.Keypoints {
+		if k.Offset < prev.Offset || k.Time < prev.Time {
+			return nil, ErrKeypoints
+		}
+		b = appendVarint(b, uint64(k.Offset-prev.Offset))
+		b = appendVarint(b, uint64(k.Time-prev.Time))
+		prev = k
+	}
+	return b, nil
+}
+
+// UnmarshalBinary decodes an index packet into x.
+func (x *Index) UnmarshalBinary(b []byte) error {
+	if !bytes.HasPrefix(b, indexMagic) {
+		return ErrMagic
+	}
+	if len(b) < indexFields {
+		return ErrShort
+	}
+
+	*x = Index{
+		Serial:      binary.LittleEndian.Uint32(b[6:]),
+		Denominator: int64(binary.LittleEndian.Uint64(b[18:])),
+		FirstTime:   int64(binary.LittleEndian.Uint64(b[26:])),
+		LastTime:    int64(binary.LittleEndian.Uint64(b[34:])),
+	}
+	n := binary.LittleEndian.Uint64(b[10:])
+	b = b[indexFields:]
+	// Each keypoint takes at least 2 bytes, which bounds the allocation.
+	if n > uint64(len(b))/2 {
+		return ErrShort
+	}
+	if n > 0 {
+		x.Keypoints = make([]Keypoint, 0, n)
+	}
+
+	var k Keypoint
+	for i := uint64(0); i < n; i++ {
+		var off, t uint64
+		var ok bool
+		off, b, ok = varint(b)
+		if !ok {
+			return ErrShort
+		}
+		t, b, ok = varint(b)
+		if !ok {
+			return ErrShort
+		}
+		k.Offset += int64(off)
+		k.Time += int64(t)
+		x.Keypoints = append(x.Keypoints, k)
+	}
+	return nil
+}
+
+// appendVarint appends v to b seven bits at a time, least significant first,
+// with the high bit set on the last byte.
+func appendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v&0x7f))
+		v >>= 7
+	}
+	return append(b, byte(v)|0x80)
+}
+
+// varint decodes a value encoded by appendVarint from the beginning of b,
+// returning the rest of b.
+func varint(b []byte) (uint64, []byte, bool) {
+	var v uint64
+	for i, c := range b {
+		if i*7 >= 64 {
+			return 0, nil, false
+		}
+		v |= uint64(c&0x7f) << (i * 7)
+		if c&0x80 != 0 {
+			return v, b[i+1:], true
+		}
+	}
+	return 0, nil, false
+}
+
+// A Skeleton is the contents of a Skeleton track.
+type Skeleton struct {
+	// Serial is the Skeleton stream's bitstream serial number.
+	Serial  uint32
+	Head    Head
+	Bones   []Bone
+	Indexes []Index
+}
+
+// ReadStream reads a Skeleton track from its logical stream, through its end.
+// The stream's first packet must be the fishead,
+// so this is usually called with a stream from an ogg.Demuxer
+// after its first packet is identified as such by ogg.IdentifyCodec.
+// Packets of unknown types are ignored.
+func ReadStream(s *ogg.LogicalStream) (*Skeleton, error) {
+	sk := &Skeleton{Serial: s.Serial}
+	p, err := s.ReadPacket()
+	if err != nil {
+		return nil, err
+	}
+	err = sk.Head.UnmarshalBinary(p.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		p, err := s.ReadPacket()
+		if err == io.EOF {
+			return sk, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case bytes.HasPrefix(p.Data, boneMagic):
+			var b Bone
+			err = b.UnmarshalBinary(p.Data)
+			if err != nil {
+				return nil, err
+			}
+			sk.Bones = append(sk.Bones, b)
+		case bytes.HasPrefix(p.Data, indexMagic):
+			var x Index
+			err = x.UnmarshalBinary(p.Data)
+			if err != nil {
+				return nil, err
+			}
+			sk.Indexes = append(sk.Indexes, x)
+		}
+	}
+}
+
+// A Track writes a Skeleton track alongside other logical streams.
+//
+// The fishead must be written first, with WriteHead,
+// and then the BOS pages of the other streams.
+// The fisbones and indexes are written next, with WriteBones,
+// followed by the other streams' remaining header pages.
+// Close ends the track, and must be called before any of the other streams' data pages.
+type Track struct {
+	e *ogg.Encoder
+}
+
+// NewTrack creates a Track with the given serial, which writes pages to w.
+func NewTrack(serial uint32, w io.Writer) *Track {
+	return &Track{ogg.NewEncoder(serial, w)}
+}
+
+// WriteHead writes the fishead on the BOS page of the track.
+func (t *Track) WriteHead(h *Head) error {
+	b, err := h.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return t.e.EncodeBOS(0, [][]byte{b})
+}
+
+// WriteBones writes fisbones and indexes as header packets of the track.
+func (t *Track) WriteBones(bones []Bone, indexes []Index) error {
+	var packets [][]byte
+	for i := range bones {
+		b, err := bones[i].MarshalBinary()
+		if err != nil {
+			return err
+		}
+		packets = append(packets, b)
+	}
+	for i := range indexes {
+		b, err := indexes[i].MarshalBinary()
+		if err != nil {
+			return err
+		}
+		packets = append(packets, b)
+	}
+	return t.e.Encode(0, packets)
+}
+
+// Close ends the track with an empty EOS page.
+// It does not close the underlying Writer.
+func (t *Track) Close() error {
+	return t.e.EncodeEOS(0, nil)
+}
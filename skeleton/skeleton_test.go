@@ -0,0 +1,193 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package skeleton
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"mccoy.space/g/ogg"
+)
+
+func TestHeadRoundTrip(t *testing.T) {
+	h := Head{
+		VersionMajor:                4,
+		PresentationTimeNumerator:   10,
+		PresentationTimeDenominator: 1000,
+		BasetimeDenominator:         1000,
+		SegmentLength:               123456,
+		ContentOffset:               4567,
+	}
+	copy(h.UTC[:], "20260101T000000.000Z")
+
+	b, err := h.MarshalBinary()
+	if err != nil {
+		t.Fatal("unexpected MarshalBinary error:", err)
+	}
+	if len(b) != 80 {
+		t.Fatal("expected 80 bytes, got", len(b))
+	}
+	var got Head
+	err = got.UnmarshalBinary(b)
+	if err != nil {
+		t.Fatal("unexpected UnmarshalBinary error:", err)
+	}
+	if got != h {
+		t.Fatalf("expected %+v, got %+v", h, got)
+	}
+
+	// Version 3 is shorter.
+	b[8] = 3
+	err = got.UnmarshalBinary(b[:64])
+	if err != nil {
+		t.Fatal("unexpected UnmarshalBinary error for version 3:", err)
+	}
+	if got.SegmentLength != 0 || got.BasetimeDenominator != 1000 {
+		t.Fatalf("version 3 head is wrong: %+v", got)
+	}
+	b[8] = 4
+	err = got.UnmarshalBinary(b[:64])
+	if err != ErrShort {
+		t.Fatal("expected ErrShort, got", err)
+	}
+}
+
+func TestBoneRoundTrip(t *testing.T) {
+	b := Bone{
+		Serial:                 7,
+		HeaderPackets:          3,
+		GranuleRateNumerator:   44100,
+		GranuleRateDenominator: 1,
+		Preroll:                2,
+	}
+	b.Set("Role", "audio/main")
+	b.Set("Content-Type", "audio/vorbis")
+	b.Set("Name", "audio_1")
+	b.Set("role", "audio/alternate")
+
+	expect := []Field{{"Content-Type", "audio/vorbis"}, {"Name", "audio_1"}, {"role", "audio/alternate"}}
+	if !reflect.DeepEqual(b.Fields, expect) {
+		t.Fatalf("expected fields %q, got %q", expect, b.Fields)
+	}
+
+	p, err := b.MarshalBinary()
+	if err != nil {
+		t.Fatal("unexpected MarshalBinary error:", err)
+	}
+	if p[8] != 44 || !bytes.HasSuffix(p, []byte("Content-Type: audio/vorbis\r\nName: audio_1\r\nrole: audio/alternate\r\n")) {
+		t.Fatalf("bytes are wrong: %q", p)
+	}
+
+	var got Bone
+	err = got.UnmarshalBinary(p)
+	if err != nil {
+		t.Fatal("unexpected UnmarshalBinary error:", err)
+	}
+	if !reflect.DeepEqual(got, b) {
+		t.Fatalf("expected %+v, got %+v", b, got)
+	}
+	if v := got.Get("content-type"); v != "audio/vorbis" {
+		t.Fatal("wrong Content-Type:", v)
+	}
+
+	err = got.UnmarshalBinary(append(p, "Bad"...))
+	if err != ErrMessageHeader {
+		t.Fatal("expected ErrMessageHeader, got", err)
+	}
+}
+
+func TestIndexRoundTrip(t *testing.T) {
+	x := Index{
+		Serial:      7,
+		Denominator: 1000,
+		FirstTime:   0,
+		LastTime:    60000,
+		Keypoints:   []Keypoint{{100, 0}, {100, 5}, {70000, 1000}, {1 << 40, 59000}},
+	}
+	b, err := x.MarshalBinary()
+	if err != nil {
+		t.Fatal("unexpected MarshalBinary error:", err)
+	}
+	// 100 and 0 fit in a byte each.
+	if !bytes.Equal(b[42:44], []byte{0xe4, 0x80}) {
+		t.Fatalf("first keypoint is wrong: % x", b[42:44])
+	}
+
+	var got Index
+	err = got.UnmarshalBinary(b)
+	if err != nil {
+		t.Fatal("unexpected UnmarshalBinary error:", err)
+	}
+	if !reflect.DeepEqual(got, x) {
+		t.Fatalf("expected %+v, got %+v", x, got)
+	}
+
+	err = got.UnmarshalBinary(b[:len(b)-1])
+	if err != ErrShort {
+		t.Fatal("expected ErrShort, got", err)
+	}
+
+	x.Keypoints[1].Time = -1
+	_, err = x.MarshalBinary()
+	if err != ErrKeypoints {
+		t.Fatal("expected ErrKeypoints, got", err)
+	}
+}
+
+func TestTrack(t *testing.T) {
+	head := Head{VersionMajor: 4, PresentationTimeDenominator: 1000, BasetimeDenominator: 1000}
+	bone := Bone{Serial: 2, HeaderPackets: 1, GranuleRateNumerator: 48000, GranuleRateDenominator: 1,
+		Fields: []Field{{"Content-Type", "audio/opus"}}}
+	index := Index{Serial: 2, Denominator: 1000, LastTime: 1000, Keypoints: []Keypoint{{0, 0}}}
+
+	var b bytes.Buffer
+	track := NewTrack(1, &b)
+	audio := ogg.NewEncoder(2, &b)
+	err := track.WriteHead(&head)
+	if err != nil {
+		t.Fatal("unexpected WriteHead error:", err)
+	}
+	err = audio.EncodeBOS(0, [][]byte{[]byte("head")})
+	if err != nil {
+		t.Fatal("unexpected EncodeBOS error:", err)
+	}
+	err = track.WriteBones([]Bone{bone}, []Index{index})
+	if err != nil {
+		t.Fatal("unexpected WriteBones error:", err)
+	}
+	err = track.Close()
+	if err != nil {
+		t.Fatal("unexpected Close error:", err)
+	}
+	err = audio.EncodeEOS(48000, [][]byte{[]byte("data")})
+	if err != nil {
+		t.Fatal("unexpected EncodeEOS error:", err)
+	}
+
+	d := ogg.NewDemuxer(&b)
+	s, err := d.NextStream()
+	if err != nil {
+		t.Fatal("unexpected NextStream error:", err)
+	}
+	a, err := d.NextStream()
+	if err != nil {
+		t.Fatal("unexpected NextStream error:", err)
+	}
+	a.Drop()
+
+	sk, err := ReadStream(s)
+	if err != nil {
+		t.Fatal("unexpected ReadStream error:", err)
+	}
+	if sk.Serial != 1 || sk.Head != head ||
+		!reflect.DeepEqual(sk.Bones, []Bone{bone}) || !reflect.DeepEqual(sk.Indexes, []Index{index}) {
+		t.Fatalf("skeleton is wrong: %+v", sk)
+	}
+
+	_, err = d.NextStream()
+	if err != io.EOF {
+		t.Fatal("expected EOF, got", err)
+	}
+}
@@ -0,0 +1,281 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+/*
+Package speex implements the Ogg encapsulation of Speex as defined in
+https://www.speex.org/docs/manual/speex-manual/node8.html :
+the header packet, the comment packet, and reading and writing Ogg Speex streams.
+*/
+package speex
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"mccoy.space/g/ogg"
+	"mccoy.space/g/ogg/comment"
+)
+
+var headerMagic = []byte("Speex   ")
+
+const headerSize = 80
+
+// ErrMagic is the error used when the header packet lacks its magic signature.
+var ErrMagic = errors.New("missing Speex header signature")
+
+// ErrShort is the error used when a header packet is truncated.
+var ErrShort = errors.New("truncated Speex header")
+
+// ErrHeader is the error used for a header with invalid fields.
+var ErrHeader = errors.New("invalid Speex header")
+
+// ErrNoStream is the error used by NewReader when there is no Speex stream.
+var ErrNoStream = errors.New("no Speex stream")
+
+// A Mode is the Speex coding mode, which determines the sample rate it's designed for.
+type Mode int32
+
+const (
+	// Narrowband is for 8 kHz.
+	Narrowband Mode = iota
+	// Wideband is for 16 kHz.
+	Wideband
+	// UltraWideband is for 32 kHz.
+	UltraWideband
+)
+
+// A Header is the header packet that begins a Speex stream.
+type Header struct {
+	// Version is the version string of the encoder, at most 20 bytes.
+	Version string
+	// VersionID is the version of the header format, which is 1.
+	VersionID int32
+	// Rate is the sample rate in Hz, which is also the rate of granule positions.
+	Rate int32
+	Mode Mode
+	// ModeBitstreamVersion is the version of the mode's bitstream.
+	ModeBitstreamVersion int32
+	// Channels is 1 or 2.
+	Channels int32
+	// Bitrate is in bits per second, or -1 if unknown.
+	Bitrate int32
+	// FrameSize is the number of samples per frame.
+	FrameSize int32
+	VBR       bool
+	// FramesPerPacket is the number of frames in each audio packet.
+	FramesPerPacket int32
+	// ExtraHeaders is the number of header packets after the comment packet.
+	ExtraHeaders int32
+}
+
+// Validate checks the ranges of h's fields.
+func (h *Header) Validate() error {
+	if len(h.Version) > 20 || h.Rate <= 0 || h.Mode < Narrowband || h.Mode > UltraWideband ||
+		h.Channels < 1 || h.Channels > 2 || h.FrameSize <= 0 || h.FramesPerPacket <= 0 ||
+		h.ExtraHeaders < 0 {
+		return ErrHeader
+	}
+	return nil
+}
+
+// MarshalBinary encodes h as a header packet, after validating it.
+func (h *Header) MarshalBinary() ([]byte, error) {
+	err := h.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, headerSize)
+	copy(b, headerMagic)
+	copy(b[8:28], h.Version)
+	vbr := int32(0)
+	if h.VBR {
+		vbr = 1
+	}
+	fields := []int32{
+		h.VersionID, headerSize, h.Rate, int32(h.Mode), h.ModeBitstreamVersion, h.Channels,
+		h.Bitrate, h.FrameSize, vbr, h.FramesPerPacket, h.ExtraHeaders, 0, 0,
+	}
+	for i, f := range fields {
+		binary.LittleEndian.PutUint32(b[28+4*i:], uint32(f))
+	}
+	return b, nil
+}
+
+// UnmarshalBinary decodes a header packet into h, and validates it.
+func (h *Header) UnmarshalBinary(b []byte) error {
+	if !bytes.HasPrefix(b, headerMagic) {
+		return ErrMagic
+	}
+	if len(b) < headerSize {
+		return ErrShort
+	}
+	field := func(off int) int32 {
+		return int32(binary.LittleEndian.Uint32(b[off:]))
+	}
+	if field(32) < headerSize {
+		return ErrHeader
+	}
+
+	*h = Header{
+		Version:              strings.TrimRight(string(b[8:28]), "\x00"),
+		VersionID:            field(28),
+		Rate:                 field(36),
+		Mode:                 Mode(field(40)),
+		ModeBitstreamVersion: field(44),
+		Channels:             field(48),
+		Bitrate:              field(52),
+		FrameSize:            field(56),
+		VBR:                  field(60) != 0,
+		FramesPerPacket:      field(64),
+		ExtraHeaders:         field(68),
+	}
+	return h.Validate()
+}
+
+// PacketSamples returns the number of samples in each audio packet.
+func (h *Header) PacketSamples() int64 {
+	return int64(h.FrameSize) * int64(h.FramesPerPacket)
+}
+
+// Time returns the time of the given granule position, which counts samples.
+func (h *Header) Time(granule int64) time.Duration {
+	return ogg.GranuleDuration(granule, int64(h.Rate), 1)
+}
+
+// A Comment is the comment packet that follows the header packet.
+// Unlike other codecs, it has no magic signature or framing bit.
+type Comment struct {
+	comment.Header
+}
+
+// MarshalBinary encodes c as a comment packet.
+func (c *Comment) MarshalBinary() ([]byte, error) {
+	return c.Header.Append(nil), nil
+}
+
+// UnmarshalBinary decodes a comment packet into c.
+func (c *Comment) UnmarshalBinary(b []byte) error {
+	h, _, err := comment.Parse(b)
+	if err != nil {
+		return err
+	}
+	c.Header = h
+	return nil
+}
+
+// A Reader reads the audio packets of an Ogg Speex stream.
+type Reader struct {
+	// Serial is the Speex stream's bitstream serial number.
+	Serial  uint32
+	Header  Header
+	Comment Comment
+	// Extra is the extra header packets, which aren't interpreted.
+	Extra [][]byte
+
+	s *ogg.LogicalStream
+}
+
+// NewReader reads the headers of the first Speex stream in r.
+// Other logical streams in r are ignored.
+func NewReader(r io.Reader) (*Reader, error) {
+	d := ogg.NewDemuxer(r)
+	s, p, err := d.FindStream(func(first []byte) bool {
+		return bytes.HasPrefix(first, headerMagic)
+	})
+	if err == io.EOF {
+		return nil, ErrNoStream
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sr := &Reader{Serial: s.Serial, s: s}
+	err = sr.Header.UnmarshalBinary(p.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err = s.ReadPacket()
+	if err != nil {
+		return nil, err
+	}
+	err = sr.Comment.UnmarshalBinary(p.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := int32(0); i < sr.Header.ExtraHeaders; i++ {
+		p, err = s.ReadPacket()
+		if err != nil {
+			return nil, err
+		}
+		sr.Extra = append(sr.Extra, p.Data)
+	}
+	return sr, nil
+}
+
+// ReadPacket returns the next audio packet.
+// The error is io.EOF after the last packet.
+func (r *Reader) ReadPacket() (ogg.Packet, error) {
+	return r.s.ReadPacket()
+}
+
+// A Writer writes an Ogg Speex stream.
+type Writer struct {
+	pw *ogg.PacketWriter
+}
+
+// NewWriter creates a Writer of an Ogg Speex stream with the given serial,
+// writing the header, comment, and extra header packets immediately,
+// so that the audio packets begin a new page.
+// The extra headers must number header.ExtraHeaders.
+func NewWriter(w io.Writer, serial uint32, header *Header, c *Comment, extra [][]byte, policy ogg.PagePolicy) (*Writer, error) {
+	hb, err := header.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	if len(extra) != int(header.ExtraHeaders) {
+		return nil, ErrHeader
+	}
+	cb, err := c.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	pw := ogg.NewPacketWriter(serial, w, policy)
+	for _, h := range append([][]byte{hb, cb}, extra...) {
+		err = pw.WritePacket(0, h)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = pw.Flush()
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{pw}, nil
+}
+
+// WritePacket writes a packet,
+// whose granule position is the number of samples through the end of the packet.
+func (w *Writer) WritePacket(granule int64, packet []byte) error {
+	return w.pw.WritePacket(granule, packet)
+}
+
+// WriteLastPacket writes a packet like WritePacket, and ends the stream with it.
+// It does not close the underlying Writer.
+func (w *Writer) WriteLastPacket(granule int64, packet []byte) error {
+	return w.pw.WriteLastPacket(granule, packet)
+}
+
+// Close writes any buffered packets and ends the stream.
+// If no packets are buffered, the end-of-stream page has an empty packet,
+// so WriteLastPacket is preferable.
+// It does not close the underlying Writer.
+func (w *Writer) Close() error {
+	return w.pw.Close()
+}
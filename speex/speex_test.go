@@ -0,0 +1,160 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package speex
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"mccoy.space/g/ogg"
+	"mccoy.space/g/ogg/comment"
+)
+
+var testHeader = Header{
+	Version:         "speex-1.2",
+	VersionID:       1,
+	Rate:            16000,
+	Mode:            Wideband,
+	Channels:        1,
+	Bitrate:         -1,
+	FrameSize:       320,
+	FramesPerPacket: 2,
+}
+
+func TestHeaderRoundTrip(t *testing.T) {
+	b, err := testHeader.MarshalBinary()
+	if err != nil {
+		t.Fatal("unexpected MarshalBinary error:", err)
+	}
+	if len(b) != 80 || string(b[:17]) != "Speex   speex-1.2" || b[32] != 80 || b[36] != 0x80 || b[37] != 0x3e {
+		t.Fatalf("bytes are wrong: % x", b)
+	}
+
+	var h Header
+	err = h.UnmarshalBinary(b)
+	if err != nil {
+		t.Fatal("unexpected UnmarshalBinary error:", err)
+	}
+	if h != testHeader {
+		t.Fatalf("expected %+v, got %+v", testHeader, h)
+	}
+
+	codec, err := ogg.IdentifyCodec(b)
+	if err != nil {
+		t.Fatal("unexpected IdentifyCodec error:", err)
+	}
+	if codec.Name != "speex" || codec.Headers != 2 || codec.Time(24000) != h.Time(24000) {
+		t.Fatalf("identification disagrees: %+v", codec)
+	}
+
+	err = h.UnmarshalBinary(b[:79])
+	if err != ErrShort {
+		t.Fatal("expected ErrShort, got", err)
+	}
+	b[48] = 3
+	err = h.UnmarshalBinary(b)
+	if err != ErrHeader {
+		t.Fatal("expected ErrHeader, got", err)
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	c := Comment{comment.Header{Vendor: "test", Comments: []string{"TITLE=Voice mail"}}}
+
+	var b bytes.Buffer
+	w, err := NewWriter(&b, 9, &testHeader, &c, nil, ogg.PagePolicy{})
+	if err != nil {
+		t.Fatal("unexpected NewWriter error:", err)
+	}
+	const n = 50
+	for i := 1; i < n; i++ {
+		err = w.WritePacket(int64(i)*testHeader.PacketSamples(), []byte{byte(i)})
+		if err != nil {
+			t.Fatal("unexpected WritePacket error:", err)
+		}
+	}
+	err = w.WriteLastPacket(n*testHeader.PacketSamples(), []byte{n})
+	if err != nil {
+		t.Fatal("unexpected WriteLastPacket error:", err)
+	}
+
+	info, err := ogg.Probe(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal("unexpected Probe error:", err)
+	}
+	if info.Duration != 2*time.Second {
+		t.Fatal("expected a 2 second duration, got", info.Duration)
+	}
+
+	r, err := NewReader(&b)
+	if err != nil {
+		t.Fatal("unexpected NewReader error:", err)
+	}
+	if r.Serial != 9 || r.Header != testHeader || !reflect.DeepEqual(r.Comment, c) {
+		t.Fatalf("headers are wrong: %d %+v %+v", r.Serial, r.Header, r.Comment)
+	}
+	for i := 1; i <= n; i++ {
+		p, err := r.ReadPacket()
+		if err != nil {
+			t.Fatal("unexpected ReadPacket error:", err)
+		}
+		if len(p.Data) != 1 || p.Data[0] != byte(i) {
+			t.Fatalf("packet %d is wrong: %v", i, p.Data)
+		}
+	}
+	_, err = r.ReadPacket()
+	if err != io.EOF {
+		t.Fatal("expected EOF, got", err)
+	}
+}
+
+func TestWriteExtraHeaders(t *testing.T) {
+	header := testHeader
+	header.ExtraHeaders = 1
+	var b bytes.Buffer
+	_, err := NewWriter(&b, 9, &header, &Comment{}, nil, ogg.PagePolicy{})
+	if err != ErrHeader {
+		t.Fatal("expected ErrHeader, got", err)
+	}
+
+	w, err := NewWriter(&b, 9, &header, &Comment{}, [][]byte{[]byte("extra")}, ogg.PagePolicy{})
+	if err != nil {
+		t.Fatal("unexpected NewWriter error:", err)
+	}
+	err = w.WriteLastPacket(header.PacketSamples(), []byte("audio"))
+	if err != nil {
+		t.Fatal("unexpected WriteLastPacket error:", err)
+	}
+
+	// The extra header ends the comment's page, and the audio has its own.
+	var pages [][]string
+	d := ogg.NewDecoder(bytes.NewReader(b.Bytes()))
+	for {
+		p, err := d.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal("unexpected Decode error:", err)
+		}
+		var packets []string
+		for _, packet := range p.Packets {
+			packets = append(packets, string(packet))
+		}
+		pages = append(pages, packets)
+	}
+	if len(pages) != 3 || len(pages[1]) != 2 || pages[1][1] != "extra" || !reflect.DeepEqual(pages[2], []string{"audio"}) {
+		t.Fatalf("pages are wrong: %q", pages)
+	}
+
+	r, err := NewReader(&b)
+	if err != nil {
+		t.Fatal("unexpected NewReader error:", err)
+	}
+	if len(r.Extra) != 1 || string(r.Extra[0]) != "extra" {
+		t.Fatalf("extra headers are wrong: %q", r.Extra)
+	}
+}
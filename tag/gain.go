@@ -0,0 +1,200 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tag
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"mccoy.space/g/ogg/comment"
+)
+
+// Reference loudness levels, in LUFS.
+const (
+	// ReplayGainReference is the level of ReplayGain tags in Vorbis streams,
+	// nominally 89 dB SPL.
+	ReplayGainReference = -18
+	// R128Reference is the level of R128 tags in Opus streams, from EBU R 128.
+	R128Reference = -23
+)
+
+// Gain tags, as in https://wiki.xiph.org/VorbisComment#Replay_Gain
+// and RFC 7845, section 5.2.1.
+const (
+	ReplayGainTrackGain = "REPLAYGAIN_TRACK_GAIN"
+	ReplayGainTrackPeak = "REPLAYGAIN_TRACK_PEAK"
+	ReplayGainAlbumGain = "REPLAYGAIN_ALBUM_GAIN"
+	ReplayGainAlbumPeak = "REPLAYGAIN_ALBUM_PEAK"
+	R128TrackGain       = "R128_TRACK_GAIN"
+	R128AlbumGain       = "R128_ALBUM_GAIN"
+)
+
+// ErrGain is the error used for a malformed gain tag,
+// or a gain that can't be stored in a stream.
+var ErrGain = errors.New("invalid gain")
+
+// A Gain is a loudness adjustment for a track or album.
+type Gain struct {
+	// DB is the gain in dB.
+	DB float64
+	// Peak is the peak amplitude, where 1 is full scale, or 0 if it's unknown.
+	// Only Vorbis streams store it.
+	Peak float64
+}
+
+// Linear returns the gain as a factor to multiply samples by.
+func (g Gain) Linear() float64 {
+	return math.Pow(10, g.DB/20)
+}
+
+// Gains are the loudness adjustments of a stream.
+type Gains struct {
+	// Track and Album are nil if the stream doesn't have them.
+	// They apply to the decoded output, after Output.
+	Track *Gain
+	Album *Gain
+	// Output is the gain in dB that Opus decoders always apply,
+	// from the Opus header. It's 0 for Vorbis.
+	Output float64
+	// Reference is the loudness in LUFS that Track and Album bring the stream to.
+	Reference float64
+}
+
+// At returns the gains adjusted to the given reference loudness.
+func (g Gains) At(reference float64) Gains {
+	shift := reference - g.Reference
+	adjust := func(x *Gain) *Gain {
+		if x == nil {
+			return nil
+		}
+		return &Gain{DB: x.DB + shift, Peak: x.Peak}
+	}
+	return Gains{Track: adjust(g.Track), Album: adjust(g.Album), Output: g.Output, Reference: reference}
+}
+
+// Gains returns the stream's gains, relative to its codec's reference level.
+func (s *Stream) Gains() (Gains, error) {
+	var g Gains
+	var err error
+	switch s.Codec {
+	case "vorbis":
+		g.Reference = ReplayGainReference
+		g.Track, err = replayGain(s.Comment, ReplayGainTrackGain, ReplayGainTrackPeak)
+		if err == nil {
+			g.Album, err = replayGain(s.Comment, ReplayGainAlbumGain, ReplayGainAlbumPeak)
+		}
+	case "opus":
+		g.Reference = R128Reference
+		g.Output = float64(s.opusHead.OutputGain) / 256
+		g.Track, err = r128Gain(s.Comment, R128TrackGain)
+		if err == nil {
+			g.Album, err = r128Gain(s.Comment, R128AlbumGain)
+		}
+	}
+	return g, err
+}
+
+// SetGains replaces the stream's gains, adjusting them to its codec's reference level.
+// A Reference of 0 means they're already at that level.
+// Vorbis streams can't have an Output gain.
+func (s *Stream) SetGains(g Gains) error {
+	switch s.Codec {
+	case "vorbis":
+		if g.Output != 0 {
+			return ErrGain
+		}
+		if g.Reference != 0 {
+			g = g.At(ReplayGainReference)
+		}
+		setReplayGain(s.Comment, g.Track, ReplayGainTrackGain, ReplayGainTrackPeak)
+		setReplayGain(s.Comment, g.Album, ReplayGainAlbumGain, ReplayGainAlbumPeak)
+
+	case "opus":
+		if g.Reference != 0 {
+			g = g.At(R128Reference)
+		}
+		output, ok := q78(g.Output)
+		if !ok {
+			return ErrGain
+		}
+		var vals [2][]string
+		for i, x := range []*Gain{g.Track, g.Album} {
+			if x == nil {
+				continue
+			}
+			v, ok := q78(x.DB)
+			if !ok {
+				return ErrGain
+			}
+			vals[i] = []string{strconv.Itoa(int(v))}
+		}
+		s.opusHead.OutputGain = output
+		s.Comment.Set(R128TrackGain, vals[0]...)
+		s.Comment.Set(R128AlbumGain, vals[1]...)
+	}
+	return nil
+}
+
+// replayGain parses a ReplayGain gain and peak, like "-6.54 dB" and "0.988".
+func replayGain(h *comment.Header, gain, peak string) (*Gain, error) {
+	gains := h.Get(gain)
+	if len(gains) == 0 {
+		return nil, nil
+	}
+	v := strings.TrimSpace(gains[0])
+	if len(v) >= 2 && strings.EqualFold(v[len(v)-2:], "dB") {
+		v = strings.TrimSpace(v[:len(v)-2])
+	}
+	db, err := strconv.ParseFloat(v, 64)
+	if err != nil || math.IsNaN(db) || math.IsInf(db, 0) {
+		return nil, ErrGain
+	}
+
+	g := &Gain{DB: db}
+	if peaks := h.Get(peak); len(peaks) > 0 {
+		g.Peak, err = strconv.ParseFloat(strings.TrimSpace(peaks[0]), 64)
+		if err != nil || !(g.Peak >= 0) || math.IsInf(g.Peak, 0) {
+			return nil, ErrGain
+		}
+	}
+	return g, nil
+}
+
+func setReplayGain(h *comment.Header, g *Gain, gain, peak string) {
+	if g == nil {
+		h.Set(gain)
+		h.Set(peak)
+		return
+	}
+	h.Set(gain, fmt.Sprintf("%+.2f dB", g.DB))
+	if g.Peak > 0 {
+		h.Set(peak, fmt.Sprintf("%.6f", g.Peak))
+	} else {
+		h.Set(peak)
+	}
+}
+
+// r128Gain parses an R128 gain, a Q7.8 integer in dB.
+func r128Gain(h *comment.Header, field string) (*Gain, error) {
+	vals := h.Get(field)
+	if len(vals) == 0 {
+		return nil, nil
+	}
+	v, err := strconv.ParseInt(strings.TrimSpace(vals[0]), 10, 16)
+	if err != nil {
+		return nil, ErrGain
+	}
+	return &Gain{DB: float64(v) / 256}, nil
+}
+
+// q78 converts dB to Q7.8, reporting whether it fits.
+func q78(db float64) (int16, bool) {
+	v := math.Round(db * 256)
+	if !(v >= math.MinInt16 && v <= math.MaxInt16) {
+		return 0, false
+	}
+	return int16(v), true
+}
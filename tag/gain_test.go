@@ -0,0 +1,192 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+
+	"mccoy.space/g/ogg"
+	"mccoy.space/g/ogg/comment"
+	"mccoy.space/g/ogg/opus"
+)
+
+func TestGains(t *testing.T) {
+	src := testFile(t)
+	streams, err := Read(bytes.NewReader(src))
+	if err != nil {
+		t.Fatal("unexpected Read error:", err)
+	}
+	if len(streams) != 2 {
+		t.Fatal("expected 2 streams, got", len(streams))
+	}
+	for _, s := range streams {
+		g, err := s.Gains()
+		if err != nil {
+			t.Fatal("unexpected Gains error:", err)
+		}
+		if g.Track != nil || g.Album != nil || g.Output != 0 {
+			t.Fatalf("%s stream has unexpected gains: %+v", s.Codec, g)
+		}
+	}
+
+	set := Gains{
+		Track:     &Gain{DB: -6.5, Peak: 0.875},
+		Album:     &Gain{DB: -7},
+		Reference: ReplayGainReference,
+	}
+	var dst bytes.Buffer
+	err = Rewrite(&dst, bytes.NewReader(src), func(s *Stream) error {
+		g := set
+		if s.Codec == "opus" {
+			g.Output = -1
+		}
+		return s.SetGains(g)
+	})
+	if err != nil {
+		t.Fatal("unexpected Rewrite error:", err)
+	}
+
+	streams, err = Read(bytes.NewReader(dst.Bytes()))
+	if err != nil {
+		t.Fatal("unexpected Read error:", err)
+	}
+	for _, s := range streams {
+		g, err := s.Gains()
+		if err != nil {
+			t.Fatal("unexpected Gains error:", err)
+		}
+		expect := set
+		switch s.Codec {
+		case "vorbis":
+			if !reflect.DeepEqual(s.Comment.Get(ReplayGainTrackGain), []string{"-6.50 dB"}) {
+				t.Fatal("wrong Vorbis track gain tag:", s.Comment.Comments)
+			}
+		case "opus":
+			if !reflect.DeepEqual(s.Comment.Get(R128TrackGain), []string{"-2944"}) {
+				t.Fatal("wrong Opus track gain tag:", s.Comment.Comments)
+			}
+			// R128 has no peaks.
+			expect = set.At(R128Reference)
+			expect.Track.Peak = 0
+			expect.Output = -1
+		}
+		if !reflect.DeepEqual(g, expect) {
+			t.Fatalf("%s stream's gains are wrong: expected %+v %+v %+v, got %+v %+v %+v",
+				s.Codec, expect, *expect.Track, *expect.Album, g, *g.Track, *g.Album)
+		}
+	}
+
+	r, err := opus.NewReader(bytes.NewReader(dst.Bytes()))
+	if err != nil {
+		t.Fatal("unexpected NewReader error:", err)
+	}
+	if r.Head.OutputGain != -256 {
+		t.Fatal("expected output gain -256, got", r.Head.OutputGain)
+	}
+
+	// Clearing the gains gives back the original file.
+	var cleared bytes.Buffer
+	err = Rewrite(&cleared, bytes.NewReader(dst.Bytes()), func(s *Stream) error {
+		return s.SetGains(Gains{})
+	})
+	if err != nil {
+		t.Fatal("unexpected Rewrite error:", err)
+	}
+	if !bytes.Equal(cleared.Bytes(), src) {
+		t.Fatal("clearing the gains didn't restore the original")
+	}
+}
+
+func TestOpusHeadExtra(t *testing.T) {
+	// RFC 7845 allows data after the OpusHead fields in versions 1 to 15.
+	head := opus.Head{Version: 1, Channels: 1, PreSkip: 312, SampleRate: 48000}
+	hb, err := head.MarshalBinary()
+	if err != nil {
+		t.Fatal("unexpected MarshalBinary error:", err)
+	}
+	hb = append(hb, "extra"...)
+	tags := opus.Tags{Header: comment.Header{Vendor: "test"}}
+	tb, err := tags.MarshalBinary()
+	if err != nil {
+		t.Fatal("unexpected MarshalBinary error:", err)
+	}
+	var src bytes.Buffer
+	e := ogg.NewEncoder(1, &src)
+	err = e.EncodeBOS(0, [][]byte{hb})
+	if err == nil {
+		err = e.Encode(0, [][]byte{tb})
+	}
+	if err == nil {
+		err = e.EncodeEOS(1272, [][]byte{{0}})
+	}
+	if err != nil {
+		t.Fatal("unexpected encoding error:", err)
+	}
+
+	// Changing only the tags keeps the OpusHead, and changing the gain patches it.
+	expect := append([]byte(nil), hb...)
+	for _, output := range []float64{0, -1} {
+		var dst bytes.Buffer
+		err = Rewrite(&dst, bytes.NewReader(src.Bytes()), func(s *Stream) error {
+			s.Comment.Add("TITLE", "New")
+			return s.SetGains(Gains{Output: output})
+		})
+		if err != nil {
+			t.Fatal("unexpected Rewrite error:", err)
+		}
+		p, err := ogg.NewDecoder(&dst).Decode()
+		if err != nil {
+			t.Fatal("unexpected Decode error:", err)
+		}
+		binary.LittleEndian.PutUint16(expect[16:], uint16(int16(output*256)))
+		if !bytes.Equal(p.Packets[0], expect) {
+			t.Fatalf("expected OpusHead %q, got %q", expect, p.Packets[0])
+		}
+	}
+}
+
+func TestGainTags(t *testing.T) {
+	tests := []struct {
+		comments []string
+		gain     *Gain
+		err      error
+	}{
+		{nil, nil, nil},
+		{[]string{"REPLAYGAIN_TRACK_GAIN=+1.5 DB"}, &Gain{DB: 1.5}, nil},
+		{[]string{"replaygain_track_gain= -3.25dB ", "REPLAYGAIN_TRACK_PEAK=1.2"}, &Gain{DB: -3.25, Peak: 1.2}, nil},
+		{[]string{"REPLAYGAIN_TRACK_PEAK=1.2"}, nil, nil},
+		{[]string{"REPLAYGAIN_TRACK_GAIN=loud"}, nil, ErrGain},
+		{[]string{"REPLAYGAIN_TRACK_GAIN=1 dB", "REPLAYGAIN_TRACK_PEAK=-1"}, nil, ErrGain},
+	}
+	for _, x := range tests {
+		s := Stream{Codec: "vorbis", Comment: &comment.Header{Comments: x.comments}}
+		g, err := s.Gains()
+		if err != x.err || !reflect.DeepEqual(g.Track, x.gain) {
+			t.Fatalf("expected %+v and %v for %q, got %+v and %v", x.gain, x.err, x.comments, g.Track, err)
+		}
+	}
+
+	s := Stream{Codec: "opus", Comment: &comment.Header{Comments: []string{"R128_ALBUM_GAIN=40000"}}}
+	_, err := s.Gains()
+	if err != ErrGain {
+		t.Fatal("expected ErrGain, got", err)
+	}
+	err = s.SetGains(Gains{Output: 200})
+	if err != ErrGain {
+		t.Fatal("expected ErrGain, got", err)
+	}
+
+	s.Codec = "vorbis"
+	err = s.SetGains(Gains{Output: 1})
+	if err != ErrGain {
+		t.Fatal("expected ErrGain, got", err)
+	}
+
+	if l := (Gain{DB: -20}).Linear(); math.Abs(l-0.1) > 1e-12 {
+		t.Fatal("expected a linear gain of 0.1, got", l)
+	}
+}
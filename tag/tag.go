@@ -0,0 +1,344 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+/*
+Package tag edits the comment headers, or tags, of the Vorbis and Opus streams in ogg files.
+
+Only the header pages of the edited streams are rewritten.
+Their later pages are renumbered, and everything else is copied unchanged.
+
+Besides the comments, streams have ReplayGain and R128 loudness gains,
+which can be read and set with Stream.Gains and Stream.SetGains.
+*/
+package tag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"mccoy.space/g/ogg"
+	"mccoy.space/g/ogg/comment"
+	"mccoy.space/g/ogg/opus"
+	"mccoy.space/g/ogg/vorbis"
+)
+
+// ErrHeaderPage is the error used when a stream's last header packet doesn't end its page,
+// as the Vorbis and Opus mappings require.
+var ErrHeaderPage = errors.New("header packets share a page with data packets")
+
+// A Stream is a logical stream whose tags are being edited.
+type Stream struct {
+	// Serial is the stream's bitstream serial number.
+	Serial uint32
+	// Codec is the name of the stream's codec, "vorbis" or "opus".
+	Codec string
+	// Comment is the stream's comment header, to be modified in place.
+	Comment *comment.Header
+
+	vorbis   vorbis.Comment
+	opus     opus.Tags
+	opusHead opus.Head
+}
+
+// Rewrite copies the ogg stream in src to dst,
+// calling edit for each Vorbis and Opus stream to change its comment header.
+// If edit returns an error, Rewrite stops and returns it.
+func Rewrite(dst io.Writer, src io.Reader, edit func(*Stream) error) error {
+	rw := rewriter{dst: dst, edit: edit, streams: map[uint32]*stream{}}
+	d := ogg.NewDecoder(src)
+	for {
+		p, err := d.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		err = rw.page(p)
+		if err != nil {
+			return err
+		}
+		err = rw.flush()
+		if err != nil {
+			return err
+		}
+	}
+
+	if rw.collecting() {
+		return io.ErrUnexpectedEOF
+	}
+	return rw.flush()
+}
+
+// Read reads the tags of the Vorbis and Opus streams in r,
+// stopping after their header pages.
+// Only the streams of the first link of a chained stream are read.
+func Read(r io.Reader) ([]*Stream, error) {
+	var streams []*Stream
+	rw := rewriter{
+		dst: io.Discard,
+		edit: func(s *Stream) error {
+			streams = append(streams, s)
+			return nil
+		},
+		streams: map[uint32]*stream{},
+	}
+	d := ogg.NewDecoder(r)
+	for {
+		p, err := d.Decode()
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		err = rw.page(p)
+		if err != nil {
+			return nil, err
+		}
+		rw.queue = nil
+		if p.Type&ogg.BOS == 0 && !rw.collecting() {
+			return streams, nil
+		}
+	}
+}
+
+// RewriteFile rewrites the file with the given name like Rewrite,
+// replacing it only once the new contents are complete.
+func RewriteFile(name string, edit func(*Stream) error) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(dst.Name())
+
+	err = Rewrite(dst, src, edit)
+	if err == nil {
+		err = dst.Sync()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	if fi, err := src.Stat(); err == nil {
+		_ = os.Chmod(dst.Name(), fi.Mode().Perm())
+	}
+	return os.Rename(dst.Name(), name)
+}
+
+// A chunk is part of the output, which may be waiting for a stream's header pages.
+type chunk struct {
+	b     []byte
+	ready bool
+}
+
+// A stream tracks an edited logical stream.
+type stream struct {
+	Stream
+	enc *ogg.Encoder
+	// headers are the complete header packets,
+	// and need is how many there are after the first.
+	headers [][]byte
+	need    int
+	// partial is true if the last of headers continues on the next page.
+	partial bool
+	// bos and slot are where the BOS page and the other header pages go in the output.
+	bos  *chunk
+	slot *chunk
+}
+
+type rewriter struct {
+	dst     io.Writer
+	edit    func(*Stream) error
+	streams map[uint32]*stream
+	queue   []*chunk
+	scratch bytes.Buffer
+}
+
+func (rw *rewriter) page(p ogg.Page) error {
+	s := rw.streams[p.Serial]
+	if p.Type&ogg.BOS != 0 {
+		s = rw.begin(p)
+		if s == nil {
+			delete(rw.streams, p.Serial)
+			rw.queue = append(rw.queue, &chunk{rawPage(&p), true})
+			return nil
+		}
+		rw.streams[p.Serial] = s
+		s.bos = &chunk{}
+		rw.queue = append(rw.queue, s.bos)
+		return nil
+	}
+
+	if s == nil {
+		rw.queue = append(rw.queue, &chunk{rawPage(&p), true})
+		return nil
+	}
+	if s.slot != nil && s.slot.ready {
+		return rw.encode(nil, func() error { return s.enc.WritePage(p) })
+	}
+
+	if s.slot == nil {
+		s.slot = &chunk{}
+		rw.queue = append(rw.queue, s.slot)
+	}
+	for i, packet := range p.Packets {
+		if i == 0 && p.Type&ogg.COP != 0 && s.partial {
+			last := len(s.headers) - 1
+			s.headers[last] = append(s.headers[last], packet...)
+		} else if len(s.headers) <= s.need {
+			s.headers = append(s.headers, append([]byte(nil), packet...))
+		} else {
+			return ErrHeaderPage
+		}
+		s.partial = p.Continued && i == len(p.Packets)-1
+	}
+	if len(s.headers) <= s.need || s.partial {
+		return nil
+	}
+	return rw.finish(s)
+}
+
+// begin starts tracking a stream from its BOS page, if it's Vorbis or Opus.
+func (rw *rewriter) begin(p ogg.Page) *stream {
+	if len(p.Packets) != 1 || p.Continued {
+		return nil
+	}
+	codec, err := ogg.IdentifyCodec(p.Packets[0])
+	if err != nil {
+		return nil
+	}
+
+	s := &stream{Stream: Stream{Serial: p.Serial, Codec: codec.Name}}
+	switch codec.Name {
+	case "vorbis":
+		s.need = 2
+	case "opus":
+		s.need = 1
+	default:
+		return nil
+	}
+	s.headers = [][]byte{append([]byte(nil), p.Packets[0]...)}
+	s.enc = ogg.NewEncoder(p.Serial, &rw.scratch)
+	return s
+}
+
+// finish edits a stream's comment header and writes its header pages into their slot.
+func (rw *rewriter) finish(s *stream) error {
+	var err error
+	switch s.Codec {
+	case "vorbis":
+		err = s.vorbis.UnmarshalBinary(s.headers[1])
+		s.Comment = &s.vorbis.Header
+	case "opus":
+		err = s.opusHead.UnmarshalBinary(s.headers[0])
+		if err == nil {
+			err = s.opus.UnmarshalBinary(s.headers[1])
+		}
+		s.Comment = &s.opus.Header
+	}
+	if err != nil {
+		return err
+	}
+
+	gain := s.opusHead.OutputGain
+	err = rw.edit(&s.Stream)
+	if err != nil {
+		return err
+	}
+
+	switch s.Codec {
+	case "vorbis":
+		s.headers[1], err = s.vorbis.MarshalBinary()
+	case "opus":
+		// The OpusHead may have data after its fields, so only the output gain is changed.
+		if s.opusHead.OutputGain != gain {
+			binary.LittleEndian.PutUint16(s.headers[0][16:], uint16(s.opusHead.OutputGain))
+		}
+		s.headers[1], err = s.opus.MarshalBinary()
+	}
+	if err != nil {
+		return err
+	}
+	err = rw.encode(s.bos, func() error { return s.enc.EncodeBOS(0, s.headers[:1]) })
+	if err != nil {
+		return err
+	}
+	err = rw.encode(s.slot, func() error { return s.enc.Encode(0, s.headers[1:]) })
+	s.headers = nil
+	return err
+}
+
+// collecting reports whether any stream's header packets are incomplete.
+func (rw *rewriter) collecting() bool {
+	for _, s := range rw.streams {
+		if !s.bos.ready {
+			return true
+		}
+	}
+	return false
+}
+
+// encode calls f to write pages with a stream's Encoder, putting them in the slot,
+// or at the end of the queue if the slot is nil.
+func (rw *rewriter) encode(slot *chunk, f func() error) error {
+	rw.scratch.Reset()
+	err := f()
+	if err != nil {
+		return err
+	}
+	b := append([]byte(nil), rw.scratch.Bytes()...)
+	if slot == nil {
+		rw.queue = append(rw.queue, &chunk{b, true})
+	} else {
+		slot.b, slot.ready = b, true
+	}
+	return nil
+}
+
+// flush writes the ready chunks at the front of the queue.
+func (rw *rewriter) flush() error {
+	for len(rw.queue) > 0 && rw.queue[0].ready {
+		_, err := rw.dst.Write(rw.queue[0].b)
+		if err != nil {
+			return err
+		}
+		rw.queue[0] = nil
+		rw.queue = rw.queue[1:]
+	}
+	return nil
+}
+
+// rawPage returns the original bytes of a decoded page.
+func rawPage(p *ogg.Page) []byte {
+	n := 27 + len(p.Segments)
+	for _, packet := range p.Packets {
+		n += len(packet)
+	}
+	b := make([]byte, 0, n)
+	b = append(b, 'O', 'g', 'g', 'S', p.Version, p.Type)
+	b = binary.LittleEndian.AppendUint64(b, uint64(p.Granule))
+	b = binary.LittleEndian.AppendUint32(b, p.Serial)
+	b = binary.LittleEndian.AppendUint32(b, p.Sequence)
+	b = binary.LittleEndian.AppendUint32(b, p.Crc)
+	b = append(b, byte(len(p.Segments)))
+	b = append(b, p.Segments...)
+	for _, packet := range p.Packets {
+		b = append(b, packet...)
+	}
+	return b
+}
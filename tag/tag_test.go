@@ -0,0 +1,310 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tag
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"mccoy.space/g/ogg"
+	"mccoy.space/g/ogg/comment"
+	"mccoy.space/g/ogg/opus"
+	"mccoy.space/g/ogg/vorbis"
+)
+
+// testFile returns a grouped ogg stream with a Vorbis stream (serial 1),
+// an Opus stream (serial 2), and a stream of another kind (serial 3).
+func testFile(t *testing.T) []byte {
+	id := vorbis.Ident{Channels: 2, SampleRate: 44100, Blocksize0: 256, Blocksize1: 2048}
+	vid, err := id.MarshalBinary()
+	if err != nil {
+		t.Fatal("unexpected MarshalBinary error:", err)
+	}
+	vc := vorbis.Comment{Header: comment.Header{Vendor: "test", Comments: []string{"TITLE=Old"}}}
+	vcb, err := vc.MarshalBinary()
+	if err != nil {
+		t.Fatal("unexpected MarshalBinary error:", err)
+	}
+	setup := append([]byte("\x05vorbis"), bytes.Repeat([]byte("setup"), 20000)...)
+
+	head := opus.Head{Version: 1, Channels: 2, PreSkip: 312, SampleRate: 48000}
+	tags := opus.Tags{Header: comment.Header{Vendor: "test", Comments: []string{"TITLE=Old"}}, Extra: []byte{1, 2, 3}}
+
+	var b bytes.Buffer
+	ve := ogg.NewEncoder(1, &b)
+	oe := ogg.NewEncoder(3, &b)
+	var ow *opus.Writer
+
+	steps := []func() error{
+		func() error { return ve.EncodeBOS(0, [][]byte{vid}) },
+		func() error { return oe.EncodeBOS(0, [][]byte{[]byte("other")}) },
+		func() (err error) {
+			ow, err = opus.NewWriter(&b, 2, &head, &tags, ogg.PagePolicy{MaxPackets: 1})
+			return err
+		},
+		func() error { return ve.Encode(0, [][]byte{vcb, setup}) },
+		func() error { return oe.Encode(1, [][]byte{[]byte("other 1")}) },
+		func() error { return ve.Encode(1000, [][]byte{{0}, {2}}) },
+		func() error {
+			for i := 1; i <= 20; i++ {
+				err := ow.WritePacket(312+int64(i)*960, bytes.Repeat([]byte{byte(i)}, 100))
+				if err != nil {
+					return err
+				}
+			}
+			return ow.Close()
+		},
+		func() error { return ve.EncodeEOS(2000, [][]byte{bytes.Repeat([]byte{4}, 70000)}) },
+		func() error { return oe.EncodeEOS(2, [][]byte{[]byte("other 2")}) },
+	}
+	for _, f := range steps {
+		err := f()
+		if err != nil {
+			t.Fatal("unexpected error building the test file:", err)
+		}
+	}
+	return b.Bytes()
+}
+
+func decodeAll(t *testing.T, b []byte) []ogg.Page {
+	var pages []ogg.Page
+	d := ogg.NewDecoder(bytes.NewReader(b))
+	for {
+		p, err := d.Decode()
+		if err == io.EOF {
+			return pages
+		}
+		if err != nil {
+			t.Fatal("unexpected Decode error:", err)
+		}
+		p.Segments = append([]byte(nil), p.Segments...)
+		packets := make([][]byte, len(p.Packets))
+		for i, packet := range p.Packets {
+			packets[i] = append([]byte(nil), packet...)
+		}
+		p.Packets = packets
+		pages = append(pages, p)
+	}
+}
+
+func readPackets(t *testing.T, b []byte) map[uint32][][]byte {
+	packets := map[uint32][][]byte{}
+	r := ogg.NewPacketReader(bytes.NewReader(b))
+	for {
+		p, err := r.ReadPacket()
+		if err == io.EOF {
+			return packets
+		}
+		if err != nil {
+			t.Fatal("unexpected ReadPacket error:", err)
+		}
+		packets[p.Serial] = append(packets[p.Serial], p.Data)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	src := testFile(t)
+	big := strings.Repeat("x", 200000)
+
+	var edited []string
+	var dst bytes.Buffer
+	err := Rewrite(&dst, bytes.NewReader(src), func(s *Stream) error {
+		edited = append(edited, s.Codec)
+		if !reflect.DeepEqual(s.Comment.Get("title"), []string{"Old"}) {
+			t.Fatalf("%s stream has the wrong title: %q", s.Codec, s.Comment.Get("title"))
+		}
+		s.Comment.Set("TITLE", "New")
+		s.Comment.Add("DESCRIPTION", big)
+		return nil
+	})
+	if err != nil {
+		t.Fatal("unexpected Rewrite error:", err)
+	}
+	if !reflect.DeepEqual(edited, []string{"opus", "vorbis"}) {
+		t.Fatal("wrong streams edited:", edited)
+	}
+
+	// Every page decodes, the sequence numbers are contiguous,
+	// and the BOS pages are first.
+	seq := map[uint32]uint32{}
+	pages := decodeAll(t, dst.Bytes())
+	for i, p := range pages {
+		if p.Sequence != seq[p.Serial] {
+			t.Fatalf("page %d of stream %d has sequence %d, expected %d", i, p.Serial, p.Sequence, seq[p.Serial])
+		}
+		seq[p.Serial]++
+		if (p.Type&ogg.BOS != 0) != (i < 3) {
+			t.Fatalf("page %d is out of order: %+v", i, p)
+		}
+	}
+
+	before, after := readPackets(t, src), readPackets(t, dst.Bytes())
+	for serial := uint32(1); serial <= 3; serial++ {
+		if len(before[serial]) != len(after[serial]) {
+			t.Fatalf("stream %d has %d packets, expected %d", serial, len(after[serial]), len(before[serial]))
+		}
+		for i := range before[serial] {
+			if serial != 3 && i == 1 {
+				continue
+			}
+			if !bytes.Equal(before[serial][i], after[serial][i]) {
+				t.Fatalf("packet %d of stream %d changed", i, serial)
+			}
+		}
+	}
+
+	var vc vorbis.Comment
+	err = vc.UnmarshalBinary(after[1][1])
+	if err != nil {
+		t.Fatal("unexpected Vorbis comment error:", err)
+	}
+	var ot opus.Tags
+	err = ot.UnmarshalBinary(after[2][1])
+	if err != nil {
+		t.Fatal("unexpected Opus tags error:", err)
+	}
+	for _, h := range []comment.Header{vc.Header, ot.Header} {
+		if !reflect.DeepEqual(h.Get("TITLE"), []string{"New"}) || !reflect.DeepEqual(h.Get("DESCRIPTION"), []string{big}) {
+			t.Fatalf("comments weren't edited: %.100q", h.Comments)
+		}
+	}
+	if !bytes.Equal(ot.Extra, []byte{1, 2, 3}) {
+		t.Fatal("Opus tags lost their extra data:", ot.Extra)
+	}
+
+	// The other stream's pages are unchanged.
+	var other, otherBefore []ogg.Page
+	for _, p := range pages {
+		if p.Serial == 3 {
+			other = append(other, p)
+		}
+	}
+	for _, p := range decodeAll(t, src) {
+		if p.Serial == 3 {
+			otherBefore = append(otherBefore, p)
+		}
+	}
+	if !reflect.DeepEqual(other, otherBefore) {
+		t.Fatal("the other stream changed")
+	}
+}
+
+func TestRewriteFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "test.ogg")
+	err := os.WriteFile(name, testFile(t), 0640)
+	if err != nil {
+		t.Fatal("unexpected WriteFile error:", err)
+	}
+
+	err = RewriteFile(name, func(s *Stream) error {
+		s.Comment.Set("ARTIST", "Someone")
+		return nil
+	})
+	if err != nil {
+		t.Fatal("unexpected RewriteFile error:", err)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal("unexpected Open error:", err)
+	}
+	defer f.Close()
+	r, err := opus.NewReader(f)
+	if err != nil {
+		t.Fatal("unexpected NewReader error:", err)
+	}
+	if !reflect.DeepEqual(r.Tags.Get("artist"), []string{"Someone"}) {
+		t.Fatal("file wasn't edited:", r.Tags.Comments)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal("unexpected Stat error:", err)
+	}
+	if fi.Mode().Perm() != 0640 {
+		t.Fatal("file mode changed:", fi.Mode())
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(name))
+	if err != nil {
+		t.Fatal("unexpected ReadDir error:", err)
+	}
+	if len(entries) != 1 {
+		t.Fatal("temporary files were left behind:", entries)
+	}
+}
+
+func TestRewriteHeaderPage(t *testing.T) {
+	head := opus.Head{Version: 1, Channels: 1}
+	hb, _ := head.MarshalBinary()
+	tags := opus.Tags{}
+	tb, _ := tags.MarshalBinary()
+
+	var b bytes.Buffer
+	e := ogg.NewEncoder(1, &b)
+	err := e.EncodeBOS(0, [][]byte{hb})
+	if err != nil {
+		t.Fatal("unexpected EncodeBOS error:", err)
+	}
+	err = e.EncodeEOS(960, [][]byte{tb, {1}})
+	if err != nil {
+		t.Fatal("unexpected EncodeEOS error:", err)
+	}
+
+	err = Rewrite(io.Discard, &b, func(*Stream) error { return nil })
+	if err != ErrHeaderPage {
+		t.Fatal("expected ErrHeaderPage, got", err)
+	}
+}
+
+func TestRewritePicture(t *testing.T) {
+	src := testFile(t)
+	art := make([]byte, 5<<20)
+	rand.New(rand.NewSource(1)).Read(art)
+	pic := comment.Picture{Type: comment.PictureFrontCover, MIMEType: "image/jpeg", Width: 3000, Height: 3000, Depth: 24, Data: art}
+
+	var dst bytes.Buffer
+	err := Rewrite(&dst, bytes.NewReader(src), func(s *Stream) error {
+		return s.Comment.AddPicture(&pic)
+	})
+	if err != nil {
+		t.Fatal("unexpected Rewrite error:", err)
+	}
+
+	// Take the picture back out, which must give the original file.
+	var stripped bytes.Buffer
+	err = Rewrite(&stripped, bytes.NewReader(dst.Bytes()), func(s *Stream) error {
+		pics, err := s.Comment.Pictures()
+		if err != nil {
+			return err
+		}
+		if len(pics) != 1 || !reflect.DeepEqual(pics[0], pic) {
+			t.Fatalf("%s stream's picture is wrong", s.Codec)
+		}
+		s.Comment.Set(comment.PictureField)
+		return nil
+	})
+	if err != nil {
+		t.Fatal("unexpected Rewrite error:", err)
+	}
+	if !bytes.Equal(stripped.Bytes(), src) {
+		t.Fatal("removing the picture didn't restore the original")
+	}
+
+	r, err := opus.NewReader(bytes.NewReader(dst.Bytes()))
+	if err != nil {
+		t.Fatal("unexpected NewReader error:", err)
+	}
+	pics, err := r.Tags.Pictures()
+	if err != nil {
+		t.Fatal("unexpected Pictures error:", err)
+	}
+	if len(pics) != 1 || !bytes.Equal(pics[0].Data, art) {
+		t.Fatal("Opus picture is wrong")
+	}
+}
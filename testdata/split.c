@@ -0,0 +1,63 @@
+/*
+ * split.c writes split.ogg with libogg, to check the Encoder's pages
+ * for packets that span pages. It's built against the source of libogg 1.3.5,
+ * in the libogg directory, with a config_types.h using stdint.h's types:
+ *
+ *	cc -I libogg/include split.c libogg/src/framing.c libogg/src/bitwise.c -o split
+ *	./split > split.ogg
+ *
+ * Each step's packets are given the same granule position,
+ * as TestLiboggSplitEncode gives them to one EncodeBOS, Encode, or EncodeEOS call,
+ * and each step is flushed, so no page holds packets from two steps.
+ * The BOS packet is small, since libogg ends the first page after the first packet
+ * and gives it granule position 0 even if the packet doesn't end there.
+ */
+#include <stdio.h>
+#include <stdlib.h>
+#include <ogg/ogg.h>
+
+struct step {
+	int bos, eos;
+	long granule;
+	long sizes[5];
+};
+
+/* The sizes end with 0; 65025 is 255 full segments, which need a terminating 0 segment. */
+static const struct step steps[] = {
+	{1, 0, 0, {30, 0}},
+	{0, 0, 0, {70000, 0}},
+	{0, 0, 1000, {100, 200, 65025, 50, 0}},
+	{0, 0, 2000, {65025, 0}},
+	{0, 1, 3000, {300, 70000, 0}},
+};
+
+int main(void) {
+	ogg_stream_state os;
+	ogg_page og;
+	ogg_packet op = {0};
+	int n = 0;
+
+	ogg_stream_init(&os, 0x5eed);
+	for (size_t i = 0; i < sizeof steps / sizeof steps[0]; i++) {
+		const struct step *s = &steps[i];
+		for (int j = 0; s->sizes[j] != 0; j++, n++) {
+			unsigned char *p = malloc(s->sizes[j]);
+			for (long k = 0; k < s->sizes[j]; k++)
+				p[k] = (unsigned char)((n * 31 + k) % 251);
+			op.packet = p;
+			op.bytes = s->sizes[j];
+			op.b_o_s = s->bos && j == 0;
+			op.e_o_s = s->eos && s->sizes[j + 1] == 0;
+			op.granulepos = s->granule;
+			ogg_stream_packetin(&os, &op);
+			op.packetno++;
+			free(p);
+		}
+		while (ogg_stream_flush(&os, &og)) {
+			fwrite(og.header, 1, og.header_len, stdout);
+			fwrite(og.body, 1, og.body_len, stdout);
+		}
+	}
+	ogg_stream_clear(&os);
+	return 0;
+}
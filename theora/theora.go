@@ -0,0 +1,321 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+/*
+Package theora implements the header packets of Theora as defined in
+https://www.theora.org/doc/Theora.pdf ,
+and the interpretation of its granule positions.
+*/
+package theora
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"time"
+
+	"mccoy.space/g/ogg"
+	"mccoy.space/g/ogg/comment"
+)
+
+var (
+	infoMagic    = []byte("\x80theora")
+	commentMagic = []byte("\x81theora")
+	setupMagic   = []byte("\x82theora")
+)
+
+// ErrMagic is the error used when a header packet lacks its magic signature.
+var ErrMagic = errors.New("missing Theora header signature")
+
+// ErrShort is the error used when a header packet is truncated.
+var ErrShort = errors.New("truncated Theora header")
+
+// ErrVersion is the error used for an info header with an incompatible version.
+var ErrVersion = errors.New("unsupported Theora version")
+
+// ErrInfo is the error used for an info header with invalid fields.
+var ErrInfo = errors.New("invalid Theora info header")
+
+// ErrNoStream is the error used by NewReader when there is no Theora stream.
+var ErrNoStream = errors.New("no Theora stream")
+
+// A ColorSpace is the color space of the encoded frames.
+type ColorSpace uint8
+
+const (
+	Unspecified ColorSpace = iota
+	// Rec470M is ITU-R Rec. 470 System M.
+	Rec470M
+	// Rec470BG is ITU-R Rec. 470 Systems B and G.
+	Rec470BG
+)
+
+// A PixelFormat is the chroma subsampling of the encoded frames.
+type PixelFormat uint8
+
+const (
+	PF420 PixelFormat = 0
+	PF422 PixelFormat = 2
+	PF444 PixelFormat = 3
+)
+
+// An Info is the identification header that begins a Theora stream.
+type Info struct {
+	VersionMajor    uint8
+	VersionMinor    uint8
+	VersionRevision uint8
+	// FrameWidth and FrameHeight are the size of the encoded frame in pixels,
+	// which are multiples of 16.
+	FrameWidth  uint32
+	FrameHeight uint32
+	// The picture region is the displayed part of the frame.
+	// PictureY is the offset from the bottom of the frame.
+	PictureWidth  uint32
+	PictureHeight uint32
+	PictureX      uint8
+	PictureY      uint8
+	// The frame rate is in frames per second.
+	FrameRateNumerator   uint32
+	FrameRateDenominator uint32
+	// The pixel aspect ratio, or 0:0 if unknown.
+	AspectNumerator   uint32
+	AspectDenominator uint32
+	ColorSpace        ColorSpace
+	// NominalBitrate is in bits per second, or 0 if unspecified.
+	NominalBitrate uint32
+	// Quality is a hint from 0 to 63.
+	Quality uint8
+	// KeyframeGranuleShift is the number of bits in granule positions
+	// for the frame offset from the last keyframe.
+	KeyframeGranuleShift uint8
+	PixelFormat          PixelFormat
+}
+
+const infoSize = 42
+
+// Validate checks info against the rules of the Theora specification.
+func (info *Info) Validate() error {
+	if info.VersionMajor != 3 || info.VersionMinor > 2 {
+		return ErrVersion
+	}
+	if info.FrameWidth == 0 || info.FrameHeight == 0 ||
+		info.FrameWidth%16 != 0 || info.FrameHeight%16 != 0 ||
+		info.FrameWidth>>4 > 0xffff || info.FrameHeight>>4 > 0xffff ||
+		info.PictureWidth+uint32(info.PictureX) > info.FrameWidth ||
+		info.PictureHeight+uint32(info.PictureY) > info.FrameHeight ||
+		info.FrameRateNumerator == 0 || info.FrameRateDenominator == 0 ||
+		info.AspectNumerator >= 1<<24 || info.AspectDenominator >= 1<<24 ||
+		info.NominalBitrate >= 1<<24 || info.Quality > 63 ||
+		info.KeyframeGranuleShift > 31 || info.PixelFormat == 1 || info.PixelFormat > 3 {
+		return ErrInfo
+	}
+	return nil
+}
+
+// MarshalBinary encodes info as an identification header packet, after validating it.
+func (info *Info) MarshalBinary() ([]byte, error) {
+	err := info.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, infoSize)
+	copy(b, infoMagic)
+	b[7], b[8], b[9] = info.VersionMajor, info.VersionMinor, info.VersionRevision
+	put16(b[10:], info.FrameWidth>>4)
+	put16(b[12:], info.FrameHeight>>4)
+	put24(b[14:], info.PictureWidth)
+	put24(b[17:], info.PictureHeight)
+	b[20], b[21] = info.PictureX, info.PictureY
+	put32(b[22:], info.FrameRateNumerator)
+	put32(b[26:], info.FrameRateDenominator)
+	put24(b[30:], info.AspectNumerator)
+	put24(b[33:], info.AspectDenominator)
+	b[36] = byte(info.ColorSpace)
+	put24(b[37:], info.NominalBitrate)
+	b[40] = info.Quality<<2 | info.KeyframeGranuleShift>>3
+	b[41] = info.KeyframeGranuleShift<<5 | byte(info.PixelFormat)<<3
+	return b, nil
+}
+
+// UnmarshalBinary decodes an identification header packet into info, and validates it.
+func (info *Info) UnmarshalBinary(b []byte) error {
+	if !bytes.HasPrefix(b, infoMagic) {
+		return ErrMagic
+	}
+	if len(b) < infoSize {
+		return ErrShort
+	}
+
+	*info = Info{
+		VersionMajor:         b[7],
+		VersionMinor:         b[8],
+		VersionRevision:      b[9],
+		FrameWidth:           get16(b[10:]) << 4,
+		FrameHeight:          get16(b[12:]) << 4,
+		PictureWidth:         get24(b[14:]),
+		PictureHeight:        get24(b[17:]),
+		PictureX:             b[20],
+		PictureY:             b[21],
+		FrameRateNumerator:   get32(b[22:]),
+		FrameRateDenominator: get32(b[26:]),
+		AspectNumerator:      get24(b[30:]),
+		AspectDenominator:    get24(b[33:]),
+		ColorSpace:           ColorSpace(b[36]),
+		NominalBitrate:       get24(b[37:]),
+		Quality:              b[40] >> 2,
+		KeyframeGranuleShift: (b[40]&0x03)<<3 | b[41]>>5,
+		PixelFormat:          PixelFormat(b[41] >> 3 & 0x03),
+	}
+	return info.Validate()
+}
+
+func put16(b []byte, v uint32) {
+	b[0], b[1] = byte(v>>8), byte(v)
+}
+
+func put24(b []byte, v uint32) {
+	b[0], b[1], b[2] = byte(v>>16), byte(v>>8), byte(v)
+}
+
+func put32(b []byte, v uint32) {
+	b[0], b[1], b[2], b[3] = byte(v>>24), byte(v>>16), byte(v>>8), byte(v)
+}
+
+func get16(b []byte) uint32 {
+	return uint32(b[0])<<8 | uint32(b[1])
+}
+
+func get24(b []byte) uint32 {
+	return uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+}
+
+func get32(b []byte) uint32 {
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+}
+
+// SplitGranule splits a granule position into the frame number of the last keyframe
+// and the number of frames since it.
+func (info *Info) SplitGranule(granule int64) (keyframe, offset int64) {
+	return granule >> info.KeyframeGranuleShift, granule & (1<<info.KeyframeGranuleShift - 1)
+}
+
+// Granule joins a keyframe number and an offset from it into a granule position.
+func (info *Info) Granule(keyframe, offset int64) int64 {
+	return keyframe<<info.KeyframeGranuleShift | offset
+}
+
+// FrameIndex returns the index of the frame with the given granule position,
+// counting from 0.
+// Before version 3.2.1, granule positions count frames from 0,
+// and since then, from 1.
+func (info *Info) FrameIndex(granule int64) int64 {
+	kf, off := info.SplitGranule(granule)
+	n := kf + off
+	if info.countsFromOne() {
+		n--
+	}
+	return n
+}
+
+// Time returns the presentation time at the end of the frame with the given granule position.
+func (info *Info) Time(granule int64) time.Duration {
+	return ogg.GranuleDuration(info.FrameIndex(granule)+1, int64(info.FrameRateNumerator), int64(info.FrameRateDenominator))
+}
+
+func (info *Info) countsFromOne() bool {
+	v := [3]uint8{info.VersionMajor, info.VersionMinor, info.VersionRevision}
+	return v[0] > 3 || v[0] == 3 && (v[1] > 2 || v[1] == 2 && v[2] >= 1)
+}
+
+// IsHeader reports whether packet is a header packet.
+func IsHeader(packet []byte) bool {
+	return len(packet) > 0 && packet[0]&0x80 != 0
+}
+
+// IsKeyframe reports whether packet is a video data packet for an intra frame.
+// An empty packet is a dropped frame, repeating the previous one, so it isn't a keyframe.
+func IsKeyframe(packet []byte) bool {
+	return len(packet) > 0 && packet[0]&0xc0 == 0
+}
+
+// A Comment is the comment header that follows the identification header.
+type Comment struct {
+	comment.Header
+}
+
+// MarshalBinary encodes c as a comment header packet.
+func (c *Comment) MarshalBinary() ([]byte, error) {
+	b := append([]byte(nil), commentMagic...)
+	return c.Header.Append(b), nil
+}
+
+// UnmarshalBinary decodes a comment header packet into c.
+func (c *Comment) UnmarshalBinary(b []byte) error {
+	if !bytes.HasPrefix(b, commentMagic) {
+		return ErrMagic
+	}
+	h, _, err := comment.Parse(b[len(commentMagic):])
+	if err != nil {
+		return err
+	}
+	c.Header = h
+	return nil
+}
+
+// A Reader reads the video packets of an Ogg Theora stream.
+type Reader struct {
+	// Serial is the Theora stream's bitstream serial number.
+	Serial  uint32
+	Info    Info
+	Comment Comment
+	// Setup is the setup header packet, which is needed for decoding but isn't parsed.
+	Setup []byte
+
+	s *ogg.LogicalStream
+}
+
+// NewReader reads the headers of the first Theora stream in r.
+// Other logical streams in r are ignored.
+func NewReader(r io.Reader) (*Reader, error) {
+	d := ogg.NewDemuxer(r)
+	s, p, err := d.FindStream(func(first []byte) bool {
+		return bytes.HasPrefix(first, infoMagic)
+	})
+	if err == io.EOF {
+		return nil, ErrNoStream
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tr := &Reader{Serial: s.Serial, s: s}
+	err = tr.Info.UnmarshalBinary(p.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err = s.ReadPacket()
+	if err != nil {
+		return nil, err
+	}
+	err = tr.Comment.UnmarshalBinary(p.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err = s.ReadPacket()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(p.Data, setupMagic) {
+		return nil, ErrMagic
+	}
+	tr.Setup = p.Data
+	return tr, nil
+}
+
+// ReadPacket returns the next video packet, which is one frame.
+// The error is io.EOF after the last packet.
+func (r *Reader) ReadPacket() (ogg.Packet, error) {
+	return r.s.ReadPacket()
+}
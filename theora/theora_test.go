@@ -0,0 +1,159 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package theora
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"mccoy.space/g/ogg"
+	"mccoy.space/g/ogg/comment"
+)
+
+var testInfo = Info{
+	VersionMajor:         3,
+	VersionMinor:         2,
+	VersionRevision:      1,
+	FrameWidth:           640,
+	FrameHeight:          368,
+	PictureWidth:         640,
+	PictureHeight:        360,
+	PictureY:             8,
+	FrameRateNumerator:   30000,
+	FrameRateDenominator: 1001,
+	AspectNumerator:      1,
+	AspectDenominator:    1,
+	ColorSpace:           Rec470BG,
+	NominalBitrate:       500000,
+	Quality:              48,
+	KeyframeGranuleShift: 6,
+	PixelFormat:          PF422,
+}
+
+func TestInfoRoundTrip(t *testing.T) {
+	b, err := testInfo.MarshalBinary()
+	if err != nil {
+		t.Fatal("unexpected MarshalBinary error:", err)
+	}
+	expect := []byte("\x80theora\x03\x02\x01\x00\x28\x00\x17\x00\x02\x80\x00\x01\x68\x00\x08" +
+		"\x00\x00\x75\x30\x00\x00\x03\xe9\x00\x00\x01\x00\x00\x01\x02\x07\xa1\x20\xc0\xd0")
+	if !bytes.Equal(b, expect) {
+		t.Fatalf("bytes are wrong:\n% x\nexpected\n% x", b, expect)
+	}
+
+	var info Info
+	err = info.UnmarshalBinary(b)
+	if err != nil {
+		t.Fatal("unexpected UnmarshalBinary error:", err)
+	}
+	if info != testInfo {
+		t.Fatalf("expected %+v, got %+v", testInfo, info)
+	}
+
+	// The identification in the ogg package must agree.
+	codec, err := ogg.IdentifyCodec(b)
+	if err != nil {
+		t.Fatal("unexpected IdentifyCodec error:", err)
+	}
+	g := info.Granule(90, 9)
+	if codec.Time(g) != info.Time(g) {
+		t.Fatalf("times disagree: %v and %v", codec.Time(g), info.Time(g))
+	}
+
+	b[8] = 3
+	err = info.UnmarshalBinary(b)
+	if err != ErrVersion {
+		t.Fatal("expected ErrVersion, got", err)
+	}
+}
+
+func TestGranules(t *testing.T) {
+	info := testInfo
+	g := info.Granule(30, 5)
+	if g != 30<<6|5 {
+		t.Fatal("wrong granule:", g)
+	}
+	kf, off := info.SplitGranule(g)
+	if kf != 30 || off != 5 {
+		t.Fatalf("expected keyframe 30 and offset 5, got %d and %d", kf, off)
+	}
+
+	// The first frame's granule position is 1 since 3.2.1.
+	if n := info.FrameIndex(info.Granule(1, 0)); n != 0 {
+		t.Fatal("expected frame 0, got", n)
+	}
+	if n := info.FrameIndex(g); n != 34 {
+		t.Fatal("expected frame 34, got", n)
+	}
+	if d := info.Time(g); d != 35*1001*time.Second/30000 {
+		t.Fatal("wrong time:", d)
+	}
+
+	info.VersionRevision = 0
+	if n := info.FrameIndex(g); n != 35 {
+		t.Fatal("expected frame 35 before 3.2.1, got", n)
+	}
+}
+
+func TestPacketTypes(t *testing.T) {
+	tests := []struct {
+		p             []byte
+		header, intra bool
+	}{
+		{[]byte{}, false, false},
+		{[]byte{0x00, 1}, false, true},
+		{[]byte{0x40, 1}, false, false},
+		{[]byte("\x82theora"), true, false},
+	}
+	for _, x := range tests {
+		if IsHeader(x.p) != x.header || IsKeyframe(x.p) != x.intra {
+			t.Fatalf("wrong type for % x", x.p)
+		}
+	}
+}
+
+func TestReader(t *testing.T) {
+	ib, _ := testInfo.MarshalBinary()
+	c := Comment{comment.Header{Vendor: "test", Comments: []string{"TITLE=Movie"}}}
+	cb, _ := c.MarshalBinary()
+	setup := []byte("\x82theora setup")
+
+	var b bytes.Buffer
+	e := ogg.NewEncoder(1, &b)
+	err := e.EncodeBOS(0, [][]byte{ib})
+	if err != nil {
+		t.Fatal("unexpected EncodeBOS error:", err)
+	}
+	err = e.Encode(0, [][]byte{cb, setup})
+	if err != nil {
+		t.Fatal("unexpected Encode error:", err)
+	}
+	err = e.EncodeEOS(testInfo.Granule(1, 1), [][]byte{{0x00}, {0x40}})
+	if err != nil {
+		t.Fatal("unexpected EncodeEOS error:", err)
+	}
+
+	r, err := NewReader(&b)
+	if err != nil {
+		t.Fatal("unexpected NewReader error:", err)
+	}
+	if r.Serial != 1 || r.Info != testInfo || !reflect.DeepEqual(r.Comment, c) || !bytes.Equal(r.Setup, setup) {
+		t.Fatalf("headers are wrong: %d %+v %+v %q", r.Serial, r.Info, r.Comment, r.Setup)
+	}
+	for _, intra := range []bool{true, false} {
+		p, err := r.ReadPacket()
+		if err != nil {
+			t.Fatal("unexpected ReadPacket error:", err)
+		}
+		if IsKeyframe(p.Data) != intra {
+			t.Fatalf("expected keyframe %v, got % x", intra, p.Data)
+		}
+	}
+	_, err = r.ReadPacket()
+	if err != io.EOF {
+		t.Fatal("expected EOF, got", err)
+	}
+}
@@ -0,0 +1,245 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package ogg
+
+import (
+	"fmt"
+	"io"
+)
+
+// A Severity is how serious a Finding is.
+type Severity int
+
+const (
+	// A Warning is for something allowed but likely to cause trouble,
+	// like a stream that ends without an EOS page.
+	Warning Severity = iota
+	// An Error is for a violation of the ogg format.
+	Error
+)
+
+func (s Severity) String() string {
+	if s == Error {
+		return "error"
+	}
+	return "warning"
+}
+
+// A Rule is a requirement of the ogg format that a stream can violate.
+type Rule int
+
+const (
+	// RuleCapture is that pages follow one another with nothing in between.
+	RuleCapture Rule = iota
+	// RuleTruncated is that the stream ends with a complete page.
+	RuleTruncated
+	// RuleCRC is that pages have correct CRCs.
+	RuleCRC
+	// RuleSegments is that pages have at least one segment.
+	RuleSegments
+	// RuleVersion is that the stream structure version is 0.
+	RuleVersion
+	// RuleHeaderType is that only the COP, BOS, and EOS bits of the header type are set.
+	RuleHeaderType
+	// RuleBOS is that each logical stream begins with one BOS page,
+	// and the BOS pages of grouped streams come before their other pages.
+	RuleBOS
+	// RuleEOS is that each logical stream ends with an EOS page, and nothing follows it.
+	RuleEOS
+	// RuleSequence is that each logical stream's page sequence numbers increase by one.
+	RuleSequence
+	// RuleGranule is that granule positions don't decrease,
+	// and pages on which no packet ends have a granule position of -1.
+	RuleGranule
+	// RuleContinuation is that pages are marked COP if and only if
+	// they continue a packet from the logical stream's previous page.
+	RuleContinuation
+)
+
+var ruleNames = [...]string{
+	RuleCapture:      "capture",
+	RuleTruncated:    "truncated",
+	RuleCRC:          "crc",
+	RuleSegments:     "segments",
+	RuleVersion:      "version",
+	RuleHeaderType:   "header-type",
+	RuleBOS:          "bos",
+	RuleEOS:          "eos",
+	RuleSequence:     "sequence",
+	RuleGranule:      "granule",
+	RuleContinuation: "continuation",
+}
+
+func (r Rule) String() string {
+	if r >= 0 && int(r) < len(ruleNames) {
+		return ruleNames[r]
+	}
+	return "rule(" + fmt.Sprint(int(r)) + ")"
+}
+
+// A Finding is a violation of a Rule found by Validate.
+type Finding struct {
+	// Offset is the position in the physical stream of the page, or of the problem if it isn't in a page.
+	Offset int64
+	// Serial and Sequence identify the page, if there is one.
+	Serial   uint32
+	Sequence uint32
+	Severity Severity
+	Rule     Rule
+	// Detail describes the specific problem.
+	Detail string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%d: serial %d page %d: %v: %v: %s", f.Offset, f.Serial, f.Sequence, f.Severity, f.Rule, f.Detail)
+}
+
+// Validate reads the ogg stream in r and reports every violation of the ogg format it finds,
+// in the order they occur.
+// Invalid pages are skipped as by a lenient Decoder and aren't otherwise checked,
+// so their absence may cause more findings about the pages after them.
+// The error is only for failures to read r.
+func Validate(r io.Reader) ([]Finding, error) {
+	v := validator{streams: map[uint32]*validStream{}}
+	d := NewLenientDecoder(r, v.damage)
+	for {
+		p, err := d.Decode()
+		if err == io.EOF {
+			v.finish(d.off)
+			return v.findings, nil
+		}
+		if err != nil {
+			return v.findings, err
+		}
+		v.page(d.Offset(), &p)
+	}
+}
+
+type validator struct {
+	findings []Finding
+	streams  map[uint32]*validStream
+	// serials are the streams of the current link, in the order of their first pages.
+	serials []uint32
+	// data is true once a page other than a BOS page is in the current link.
+	data bool
+}
+
+// A validStream is the state of a logical stream being validated.
+type validStream struct {
+	seq     uint32
+	granule int64
+	// pending is true if the last page's last packet continues on the next page.
+	pending bool
+	eos     bool
+}
+
+func (v *validator) add(off int64, serial, seq uint32, sev Severity, rule Rule, detail string) {
+	v.findings = append(v.findings, Finding{off, serial, seq, sev, rule, detail})
+}
+
+// damage adds a finding for a region skipped by the Decoder.
+func (v *validator) damage(dmg Damage) {
+	add := func(rule Rule, detail string) {
+		v.add(dmg.Offset, dmg.Serial, dmg.Sequence, Error, rule, detail)
+	}
+
+	switch dmg.Err {
+	case nil:
+		add(RuleCapture, fmt.Sprintf("%d bytes outside of pages", dmg.Size))
+	case ErrBadSegs:
+		add(RuleSegments, fmt.Sprintf("page has no segments; skipped %d bytes", dmg.Size))
+	case io.ErrUnexpectedEOF:
+		add(RuleTruncated, fmt.Sprintf("stream ends in a page; skipped %d bytes", dmg.Size))
+	default:
+		add(RuleCRC, fmt.Sprintf("%v; skipped %d bytes", dmg.Err, dmg.Size))
+	}
+}
+
+func (v *validator) page(off int64, p *Page) {
+	add := func(sev Severity, rule Rule, detail string) {
+		v.add(off, p.Serial, p.Sequence, sev, rule, detail)
+	}
+
+	if p.Version != 0 {
+		add(Error, RuleVersion, fmt.Sprintf("stream structure version is %d", p.Version))
+	}
+	if extra := p.Type &^ (COP | BOS | EOS); extra != 0 {
+		add(Error, RuleHeaderType, fmt.Sprintf("header type has unknown bits %#02x", extra))
+	}
+
+	s := v.streams[p.Serial]
+	if p.Type&BOS != 0 {
+		if v.ended() {
+			// A new link of a chained stream
+			v.streams = map[uint32]*validStream{}
+			v.serials = nil
+			v.data = false
+			s = nil
+		}
+		if s != nil {
+			add(Error, RuleBOS, "duplicate BOS page")
+		} else if v.data {
+			add(Error, RuleBOS, "BOS page after other pages of its link")
+		}
+	} else {
+		v.data = true
+		if s == nil {
+			add(Error, RuleBOS, "stream doesn't begin with a BOS page")
+		}
+	}
+
+	if s == nil {
+		s = &validStream{granule: -1}
+		v.streams[p.Serial] = s
+		v.serials = append(v.serials, p.Serial)
+		if p.Type&COP != 0 {
+			add(Error, RuleContinuation, "first page continues a packet")
+		}
+	} else {
+		if s.eos {
+			add(Error, RuleEOS, "page after the EOS page")
+		}
+		if p.Sequence != s.seq+1 {
+			add(Error, RuleSequence, fmt.Sprintf("expected sequence %d", s.seq+1))
+		}
+		if p.Type&COP != 0 && !s.pending {
+			add(Error, RuleContinuation, "COP page without a packet to continue")
+		} else if p.Type&COP == 0 && s.pending {
+			add(Error, RuleContinuation, "previous page's last packet isn't continued")
+		}
+	}
+
+	ends := !(p.Continued && len(p.Packets) == 1)
+	if p.Granule != -1 {
+		if !ends {
+			add(Warning, RuleGranule, fmt.Sprintf("granule position is %d on a page where no packet ends", p.Granule))
+		}
+		if p.Granule < s.granule {
+			add(Error, RuleGranule, fmt.Sprintf("granule position decreased from %d to %d", s.granule, p.Granule))
+		}
+		s.granule = p.Granule
+	}
+
+	s.seq = p.Sequence
+	s.pending = p.Continued
+	s.eos = s.eos || p.Type&EOS != 0
+}
+
+// ended reports whether every logical stream has ended.
+func (v *validator) ended() bool {
+	for _, s := range v.streams {
+		if !s.eos {
+			return false
+		}
+	}
+	return true
+}
+
+// finish reports the logical streams that didn't end at the end of the physical stream.
+func (v *validator) finish(off int64) {
+	for _, serial := range v.serials {
+		if s := v.streams[serial]; !s.eos {
+			v.add(off, serial, s.seq, Warning, RuleEOS, "stream has no EOS page")
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package ogg
+
+import (
+	"bytes"
+	"testing"
+)
+
+// testPage encodes p as page seq of the given stream,
+// then changes its bytes with f, if it's not nil, and fixes the CRC.
+func testPage(t *testing.T, serial, seq uint32, p Page, f func([]byte) []byte) []byte {
+	var b bytes.Buffer
+	e := NewEncoder(serial, &b)
+	e.page = seq
+	err := e.WritePage(p)
+	if err != nil {
+		t.Fatal("unexpected WritePage error:", err)
+	}
+	page := b.Bytes()
+	if f != nil {
+		page = f(page)
+		byteOrder.PutUint32(page[22:], 0)
+		byteOrder.PutUint32(page[22:], crc32(page))
+	}
+	return page
+}
+
+func TestValidateClean(t *testing.T) {
+	var b bytes.Buffer
+	e1, e2 := NewEncoder(1, &b), NewEncoder(2, &b)
+	steps := []func() error{
+		func() error { return e1.EncodeBOS(0, nil) },
+		func() error { return e2.EncodeBOS(0, nil) },
+		func() error { return e1.Encode(10, [][]byte{make([]byte, 70000)}) },
+		func() error { return e2.EncodeEOS(5, [][]byte{{1}}) },
+		func() error { return e1.EncodeEOS(20, nil) },
+		// A chained link, reusing a serial.
+		func() error { e2 = NewEncoder(2, &b); return e2.EncodeBOS(0, nil) },
+		func() error { return e2.EncodeEOS(1, nil) },
+	}
+	for _, f := range steps {
+		err := f()
+		if err != nil {
+			t.Fatal("unexpected error building the stream:", err)
+		}
+	}
+
+	findings, err := Validate(&b)
+	if err != nil {
+		t.Fatal("unexpected Validate error:", err)
+	}
+	if len(findings) != 0 {
+		t.Fatal("unexpected findings:", findings)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	empty := [][]byte{nil}
+	data := Page{Granule: 10, Packets: [][]byte{[]byte("data")}}
+	setVersion := func(b []byte) []byte { b[4] = 1; return b }
+	setCOP := func(b []byte) []byte { b[5] |= COP; return b }
+	noSegs := func(b []byte) []byte { b[26] = 0; return b[:headsz] }
+
+	badCrc := testPage(t, 1, 3, data, nil)
+	badCrc[len(badCrc)-1]++
+
+	parts := [][]byte{
+		testPage(t, 1, 0, Page{Type: BOS, Packets: empty}, nil),
+		testPage(t, 2, 0, Page{Type: BOS, Packets: empty}, nil),
+		testPage(t, 1, 1, data, nil),
+		[]byte("junk!"),
+		testPage(t, 1, 2, data, setVersion),
+		badCrc,
+		testPage(t, 1, 4, data, noSegs),
+		testPage(t, 2, 1, data, setCOP),
+		testPage(t, 1, 5, Page{Granule: 5, Packets: empty}, nil),
+		testPage(t, 3, 0, Page{Type: BOS, Packets: empty}, nil),
+		testPage(t, 4, 0, data, nil),
+		testPage(t, 1, 6, Page{Granule: 20, Packets: [][]byte{make([]byte, 255)}, Continued: true}, nil),
+		testPage(t, 1, 7, Page{Type: EOS | 0x08, Granule: 20, Packets: empty}, nil),
+		testPage(t, 1, 8, Page{Granule: 30, Packets: empty}, nil),
+		testPage(t, 2, 2, data, nil)[:30],
+	}
+	var b bytes.Buffer
+	var starts []int64
+	for _, p := range parts {
+		starts = append(starts, int64(b.Len()))
+		b.Write(p)
+	}
+	starts = append(starts, int64(b.Len()))
+
+	expect := []struct {
+		part     int
+		serial   uint32
+		seq      uint32
+		severity Severity
+		rule     Rule
+	}{
+		{3, 0, 0, Error, RuleCapture},
+		{4, 1, 2, Error, RuleVersion},
+		{5, 1, 3, Error, RuleCRC},
+		{6, 1, 4, Error, RuleSegments},
+		{7, 2, 1, Error, RuleContinuation},
+		{8, 1, 5, Error, RuleSequence},
+		{8, 1, 5, Error, RuleGranule},
+		{9, 3, 0, Error, RuleBOS},
+		{10, 4, 0, Error, RuleBOS},
+		{11, 1, 6, Warning, RuleGranule},
+		{12, 1, 7, Error, RuleHeaderType},
+		{12, 1, 7, Error, RuleContinuation},
+		{13, 1, 8, Error, RuleEOS},
+		{14, 2, 2, Error, RuleTruncated},
+		{15, 2, 1, Warning, RuleEOS},
+		{15, 3, 0, Warning, RuleEOS},
+		{15, 4, 0, Warning, RuleEOS},
+	}
+
+	findings, err := Validate(&b)
+	if err != nil {
+		t.Fatal("unexpected Validate error:", err)
+	}
+	if len(findings) != len(expect) {
+		t.Fatalf("expected %d findings, got %d: %v", len(expect), len(findings), findings)
+	}
+	for i, x := range expect {
+		f := findings[i]
+		if f.Offset != starts[x.part] || f.Serial != x.serial || f.Sequence != x.seq || f.Severity != x.severity || f.Rule != x.rule {
+			t.Fatalf("finding %d: expected %v %v at %d for page %d of stream %d, got %v",
+				i, x.severity, x.rule, starts[x.part], x.seq, x.serial, f)
+		}
+	}
+}
+
+func TestValidateTruncated(t *testing.T) {
+	bos := testPage(t, 1, 0, Page{Type: BOS, Packets: [][]byte{nil}}, nil)
+	data := testPage(t, 1, 1, Page{Granule: 10, Packets: [][]byte{[]byte("data")}}, nil)
+
+	// Cut right after the second page's header, and right after its segment table.
+	for _, cut := range []int{headsz, headsz + 1} {
+		stream := append(append([]byte(nil), bos...), data[:cut]...)
+		findings, err := Validate(bytes.NewReader(stream))
+		if err != nil {
+			t.Fatal("unexpected Validate error:", err)
+		}
+		if len(findings) == 0 {
+			t.Fatal("expected findings, got none")
+		}
+		f := findings[0]
+		if f.Offset != int64(len(bos)) || f.Serial != 1 || f.Sequence != 1 || f.Severity != Error || f.Rule != RuleTruncated {
+			t.Fatalf("expected Error %v at %d for page 1 of stream 1, got %v", RuleTruncated, len(bos), f)
+		}
+	}
+}
@@ -0,0 +1,547 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+/*
+Package vorbis implements the header packets of Vorbis I as defined in
+https://xiph.org/vorbis/doc/Vorbis_I_spec.html :
+the identification header, the comment header,
+and enough of the setup header to count the samples of each audio packet.
+*/
+package vorbis
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/bits"
+
+	"mccoy.space/g/ogg"
+	"mccoy.space/g/ogg/comment"
+)
+
+var (
+	identMagic   = []byte("\x01vorbis")
+	commentMagic = []byte("\x03vorbis")
+	setupMagic   = []byte("\x05vorbis")
+)
+
+// ErrMagic is the error used when a header packet lacks its magic signature.
+var ErrMagic = errors.New("missing Vorbis header signature")
+
+// ErrShort is the error used when a header packet is truncated.
+var ErrShort = errors.New("truncated Vorbis header")
+
+// ErrFraming is the error used when a header packet's framing bit isn't set.
+var ErrFraming = errors.New("missing Vorbis framing bit")
+
+// ErrIdent is the error used for an identification header with invalid fields.
+var ErrIdent = errors.New("invalid Vorbis identification header")
+
+// ErrSetup is the error used for a malformed setup header.
+var ErrSetup = errors.New("invalid Vorbis setup header")
+
+// ErrNotAudio is the error used by a Counter when a packet isn't an audio packet.
+var ErrNotAudio = errors.New("not a Vorbis audio packet")
+
+// ErrNoStream is the error used by NewReader when there is no Vorbis stream.
+var ErrNoStream = errors.New("no Vorbis stream")
+
+// An Ident is the identification header that begins a Vorbis stream.
+type Ident struct {
+	// Version is the Vorbis version, which must be 0.
+	Version uint32
+	// Channels is the number of audio channels.
+	Channels uint8
+	// SampleRate is the sample rate in Hz, which is also the rate of granule positions.
+	SampleRate uint32
+	// The bitrates are hints in bits per second, or 0 if unset.
+	BitrateMaximum int32
+	BitrateNominal int32
+	BitrateMinimum int32
+	// Blocksize0 and Blocksize1 are the short and long block sizes in samples.
+	// They're powers of two from 64 to 8192, and Blocksize0 is not greater than Blocksize1.
+	Blocksize0 int
+	Blocksize1 int
+}
+
+// Validate checks id against the rules of the Vorbis I specification.
+func (id *Ident) Validate() error {
+	if id.Version != 0 || id.Channels == 0 || id.SampleRate == 0 {
+		return ErrIdent
+	}
+	for _, bs := range []int{id.Blocksize0, id.Blocksize1} {
+		if bs < 64 || bs > 8192 || bs&(bs-1) != 0 {
+			return ErrIdent
+		}
+	}
+	if id.Blocksize0 > id.Blocksize1 {
+		return ErrIdent
+	}
+	return nil
+}
+
+// MarshalBinary encodes id as an identification header packet, after validating it.
+func (id *Ident) MarshalBinary() ([]byte, error) {
+	err := id.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 0, 30)
+	b = append(b, identMagic...)
+	b = binary.LittleEndian.AppendUint32(b, id.Version)
+	b = append(b, id.Channels)
+	b = binary.LittleEndian.AppendUint32(b, id.SampleRate)
+	b = binary.LittleEndian.AppendUint32(b, uint32(id.BitrateMaximum))
+	b = binary.LittleEndian.AppendUint32(b, uint32(id.BitrateNominal))
+	b = binary.LittleEndian.AppendUint32(b, uint32(id.BitrateMinimum))
+	b = append(b, byte(bits.TrailingZeros(uint(id.Blocksize1)))<<4|byte(bits.TrailingZeros(uint(id.Blocksize0))))
+	b = append(b, 1)
+	return b, nil
+}
+
+// UnmarshalBinary decodes an identification header packet into id, and validates it.
+func (id *Ident) UnmarshalBinary(b []byte) error {
+	if !bytes.HasPrefix(b, identMagic) {
+		return ErrMagic
+	}
+	if len(b) < 30 {
+		return ErrShort
+	}
+	if b[29]&1 == 0 {
+		return ErrFraming
+	}
+
+	*id = Ident{
+		Version:        binary.LittleEndian.Uint32(b[7:]),
+		Channels:       b[11],
+		SampleRate:     binary.LittleEndian.Uint32(b[12:]),
+		BitrateMaximum: int32(binary.LittleEndian.Uint32(b[16:])),
+		BitrateNominal: int32(binary.LittleEndian.Uint32(b[20:])),
+		BitrateMinimum: int32(binary.LittleEndian.Uint32(b[24:])),
+		Blocksize0:     1 << (b[28] & 0x0f),
+		Blocksize1:     1 << (b[28] >> 4),
+	}
+	return id.Validate()
+}
+
+// A Comment is the comment header that follows the identification header.
+type Comment struct {
+	comment.Header
+}
+
+// MarshalBinary encodes c as a comment header packet.
+func (c *Comment) MarshalBinary() ([]byte, error) {
+	b := append([]byte(nil), commentMagic...)
+	b = c.Header.Append(b)
+	b = append(b, 1)
+	return b, nil
+}
+
+// UnmarshalBinary decodes a comment header packet into c.
+func (c *Comment) UnmarshalBinary(b []byte) error {
+	if !bytes.HasPrefix(b, commentMagic) {
+		return ErrMagic
+	}
+	h, n, err := comment.Parse(b[len(commentMagic):])
+	if err != nil {
+		return err
+	}
+	rest := b[len(commentMagic)+n:]
+	if len(rest) == 0 {
+		return ErrShort
+	}
+	if rest[0]&1 == 0 {
+		return ErrFraming
+	}
+	c.Header = h
+	return nil
+}
+
+// A Mode is a mode configuration from the setup header.
+type Mode struct {
+	// BlockFlag is whether audio packets in this mode use Blocksize1 rather than Blocksize0.
+	BlockFlag     bool
+	WindowType    uint16
+	TransformType uint16
+	Mapping       uint8
+}
+
+// A Setup is the part of the setup header, the third header packet,
+// needed to find the size of each audio packet's block.
+// The codebooks, floors, residues, and mappings are checked, but not kept.
+type Setup struct {
+	Modes []Mode
+}
+
+// ParseSetup parses a setup header packet for a stream with the given identification header.
+func ParseSetup(id *Ident, b []byte) (*Setup, error) {
+	if !bytes.HasPrefix(b, setupMagic) {
+		return nil, ErrMagic
+	}
+	r := bitReader{b: b[len(setupMagic):]}
+
+	books := int(r.read(8)) + 1
+	for i := 0; i < books && r.err == nil; i++ {
+		skipCodebook(&r)
+	}
+
+	times := int(r.read(6)) + 1
+	for i := 0; i < times; i++ {
+		if r.read(16) != 0 {
+			r.fail(ErrSetup)
+		}
+	}
+
+	floors := int(r.read(6)) + 1
+	for i := 0; i < floors && r.err == nil; i++ {
+		skipFloor(&r)
+	}
+
+	residues := int(r.read(6)) + 1
+	for i := 0; i < residues && r.err == nil; i++ {
+		skipResidue(&r)
+	}
+
+	mappings := int(r.read(6)) + 1
+	for i := 0; i < mappings && r.err == nil; i++ {
+		skipMapping(&r, int(id.Channels))
+	}
+
+	s := &Setup{Modes: make([]Mode, int(r.read(6))+1)}
+	for i := range s.Modes {
+		s.Modes[i] = Mode{
+			BlockFlag:     r.read(1) == 1,
+			WindowType:    uint16(r.read(16)),
+			TransformType: uint16(r.read(16)),
+			Mapping:       uint8(r.read(8)),
+		}
+		if int(s.Modes[i].Mapping) >= mappings {
+			r.fail(ErrSetup)
+		}
+	}
+
+	if r.read(1) != 1 {
+		r.fail(ErrFraming)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return s, nil
+}
+
+func skipCodebook(r *bitReader) {
+	if r.read(24) != 0x564342 {
+		r.fail(ErrSetup)
+		return
+	}
+	dims := int(r.read(16))
+	entries := int(r.read(24))
+
+	if r.read(1) == 0 {
+		sparse := r.read(1) == 1
+		for i := 0; i < entries && r.err == nil; i++ {
+			if !sparse || r.read(1) == 1 {
+				r.read(5)
+			}
+		}
+	} else {
+		r.read(5)
+		for i := 0; i < entries && r.err == nil; {
+			i += int(r.read(ilog(uint32(entries - i))))
+			if i > entries {
+				r.fail(ErrSetup)
+			}
+		}
+	}
+
+	switch r.read(4) {
+	case 0:
+	case 1:
+		r.read(32)
+		r.read(32)
+		valueBits := int(r.read(4)) + 1
+		r.read(1)
+		n, ok := lookup1Values(entries, dims)
+		if !ok {
+			r.fail(ErrSetup)
+			return
+		}
+		r.skip(n * valueBits)
+	case 2:
+		r.read(32)
+		r.read(32)
+		valueBits := int(r.read(4)) + 1
+		r.read(1)
+		r.skip(entries * dims * valueBits)
+	default:
+		r.fail(ErrSetup)
+	}
+}
+
+// lookup1Values returns the greatest n such that n to the power dims is at most entries.
+func lookup1Values(entries, dims int) (int, bool) {
+	if dims == 0 {
+		return 0, false
+	}
+	pow := func(n int) int {
+		p := 1
+		for i := 0; i < dims; i++ {
+			p *= n
+			if p > entries {
+				break
+			}
+		}
+		return p
+	}
+	n := 0
+	for pow(n+1) <= entries {
+		n++
+	}
+	return n, true
+}
+
+func skipFloor(r *bitReader) {
+	switch r.read(16) {
+	case 0:
+		r.read(8)
+		r.read(16)
+		r.read(16)
+		r.read(6)
+		r.read(8)
+		books := int(r.read(4)) + 1
+		r.skip(books * 8)
+	case 1:
+		partitions := int(r.read(5))
+		classes := make([]int, partitions)
+		maxClass := -1
+		for i := range classes {
+			classes[i] = int(r.read(4))
+			if classes[i] > maxClass {
+				maxClass = classes[i]
+			}
+		}
+		dims := make([]int, maxClass+1)
+		for i := range dims {
+			dims[i] = int(r.read(3)) + 1
+			subclasses := r.read(2)
+			if subclasses != 0 {
+				r.read(8)
+			}
+			r.skip((1 << subclasses) * 8)
+		}
+		r.read(2)
+		rangeBits := int(r.read(4))
+		for _, c := range classes {
+			r.skip(dims[c] * rangeBits)
+		}
+	default:
+		r.fail(ErrSetup)
+	}
+}
+
+func skipResidue(r *bitReader) {
+	if r.read(16) > 2 {
+		r.fail(ErrSetup)
+		return
+	}
+	r.read(24)
+	r.read(24)
+	r.read(24)
+	classifications := int(r.read(6)) + 1
+	r.read(8)
+
+	books := 0
+	for i := 0; i < classifications; i++ {
+		cascade := r.read(3)
+		if r.read(1) == 1 {
+			cascade |= r.read(5) << 3
+		}
+		books += bits.OnesCount32(cascade)
+	}
+	r.skip(books * 8)
+}
+
+func skipMapping(r *bitReader, channels int) {
+	if r.read(16) != 0 {
+		r.fail(ErrSetup)
+		return
+	}
+	submaps := 1
+	if r.read(1) == 1 {
+		submaps = int(r.read(4)) + 1
+	}
+	if r.read(1) == 1 {
+		steps := int(r.read(8)) + 1
+		r.skip(steps * 2 * ilog(uint32(channels-1)))
+	}
+	if r.read(2) != 0 {
+		r.fail(ErrSetup)
+		return
+	}
+	if submaps > 1 {
+		r.skip(channels * 4)
+	}
+	r.skip(submaps * 3 * 8)
+}
+
+// ilog returns the number of bits needed to represent x.
+func ilog(x uint32) int {
+	return bits.Len32(x)
+}
+
+// A bitReader reads the bit-packed fields of Vorbis packets, least significant bit first.
+// After reading past the end of b, err is ErrShort, and reads return 0.
+type bitReader struct {
+	b   []byte
+	pos int
+	err error
+}
+
+func (r *bitReader) read(n int) uint32 {
+	if r.err != nil {
+		return 0
+	}
+	if n > len(r.b)*8-r.pos {
+		r.err = ErrShort
+		return 0
+	}
+	var v uint32
+	for i := 0; i < n; {
+		byt := r.b[r.pos/8] >> (r.pos % 8)
+		take := 8 - r.pos%8
+		if take > n-i {
+			take = n - i
+		}
+		v |= uint32(byt&(1<<take-1)) << i
+		i += take
+		r.pos += take
+	}
+	return v
+}
+
+func (r *bitReader) skip(n int) {
+	if r.err != nil {
+		return
+	}
+	if n > len(r.b)*8-r.pos {
+		r.err = ErrShort
+		return
+	}
+	r.pos += n
+}
+
+func (r *bitReader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// A Counter counts the samples decoded from each audio packet of a stream.
+type Counter struct {
+	id    *Ident
+	setup *Setup
+	prev  int
+}
+
+// NewCounter creates a Counter for a stream with the given headers,
+// starting before its first audio packet.
+func NewCounter(id *Ident, setup *Setup) *Counter {
+	return &Counter{id: id, setup: setup}
+}
+
+// BlockSize returns the size of the block of the given audio packet.
+func (c *Counter) BlockSize(packet []byte) (int, error) {
+	if len(packet) == 0 || packet[0]&1 != 0 {
+		return 0, ErrNotAudio
+	}
+	r := bitReader{b: packet, pos: 1}
+	mode := int(r.read(ilog(uint32(len(c.setup.Modes) - 1))))
+	if r.err != nil || mode >= len(c.setup.Modes) {
+		return 0, ErrNotAudio
+	}
+	if c.setup.Modes[mode].BlockFlag {
+		return c.id.Blocksize1, nil
+	}
+	return c.id.Blocksize0, nil
+}
+
+// Samples returns the number of samples decoded from the given audio packet,
+// which must follow the previous packet given to Samples.
+// The first audio packet of a stream decodes no samples.
+// The last page's granule position may cut the last packet short,
+// which isn't accounted for.
+func (c *Counter) Samples(packet []byte) (int, error) {
+	bs, err := c.BlockSize(packet)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	if c.prev != 0 {
+		n = c.prev/4 + bs/4
+	}
+	c.prev = bs
+	return n, nil
+}
+
+// A Reader reads the audio packets of an Ogg Vorbis stream.
+type Reader struct {
+	// Serial is the Vorbis stream's bitstream serial number.
+	Serial  uint32
+	Ident   Ident
+	Comment Comment
+	Setup   *Setup
+
+	s *ogg.LogicalStream
+}
+
+// NewReader reads the headers of the first Vorbis stream in r.
+// Other logical streams in r are ignored.
+func NewReader(r io.Reader) (*Reader, error) {
+	d := ogg.NewDemuxer(r)
+	s, p, err := d.FindStream(func(first []byte) bool {
+		return bytes.HasPrefix(first, identMagic)
+	})
+	if err == io.EOF {
+		return nil, ErrNoStream
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	vr := &Reader{Serial: s.Serial, s: s}
+	err = vr.Ident.UnmarshalBinary(p.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err = s.ReadPacket()
+	if err != nil {
+		return nil, err
+	}
+	err = vr.Comment.UnmarshalBinary(p.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err = s.ReadPacket()
+	if err != nil {
+		return nil, err
+	}
+	vr.Setup, err = ParseSetup(&vr.Ident, p.Data)
+	if err != nil {
+		return nil, err
+	}
+	return vr, nil
+}
+
+// ReadPacket returns the next audio packet.
+// The error is io.EOF after the last packet.
+func (r *Reader) ReadPacket() (ogg.Packet, error) {
+	return r.s.ReadPacket()
+}
+
+// NewCounter creates a Counter for the stream's audio packets.
+func (r *Reader) NewCounter() *Counter {
+	return NewCounter(&r.Ident, r.Setup)
+}
@@ -0,0 +1,271 @@
+// © 2026 Steve McCoy under the MIT license. See LICENSE for details.
+
+package vorbis
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"mccoy.space/g/ogg"
+	"mccoy.space/g/ogg/comment"
+)
+
+// A bitWriter packs fields like a Vorbis encoder, least significant bit first.
+type bitWriter struct {
+	b   []byte
+	pos int
+}
+
+func (w *bitWriter) write(n int, v uint32) {
+	for i := 0; i < n; i++ {
+		if w.pos%8 == 0 {
+			w.b = append(w.b, 0)
+		}
+		w.b[len(w.b)-1] |= byte(v>>i&1) << (w.pos % 8)
+		w.pos++
+	}
+}
+
+// testSetup returns a setup header exercising most of the structures,
+// with one short block mode and one long.
+func testSetup() []byte {
+	w := bitWriter{b: append([]byte(nil), setupMagic...), pos: len(setupMagic) * 8}
+
+	w.write(8, 2-1)
+	// An unordered, sparse codebook with a type 1 lookup.
+	w.write(24, 0x564342)
+	w.write(16, 2)
+	w.write(24, 10)
+	w.write(1, 0)
+	w.write(1, 1)
+	for i := 0; i < 10; i++ {
+		w.write(1, uint32(i%2))
+		if i%2 == 1 {
+			w.write(5, 3)
+		}
+	}
+	w.write(4, 1)
+	w.write(32, 0)
+	w.write(32, 0)
+	w.write(4, 5-1)
+	w.write(1, 0)
+	w.write(3*5, 0) // 3 lookup values, since 3² ≤ 10 < 4²
+	// An ordered codebook with a type 2 lookup.
+	w.write(24, 0x564342)
+	w.write(16, 1)
+	w.write(24, 5)
+	w.write(1, 1)
+	w.write(5, 1)
+	w.write(3, 2) // ilog(5-0)
+	w.write(2, 3) // ilog(5-2)
+	w.write(4, 2)
+	w.write(32, 0)
+	w.write(32, 0)
+	w.write(4, 3-1)
+	w.write(1, 0)
+	w.write(5*1*3, 0)
+
+	w.write(6, 1-1)
+	w.write(16, 0)
+
+	w.write(6, 2-1)
+	// Floor 0.
+	w.write(16, 0)
+	w.write(8, 0)
+	w.write(16, 0)
+	w.write(16, 0)
+	w.write(6, 0)
+	w.write(8, 0)
+	w.write(4, 2-1)
+	w.write(2*8, 0)
+	// Floor 1, with two partitions of class 0 and one of class 1.
+	w.write(16, 1)
+	w.write(5, 3)
+	w.write(4, 0)
+	w.write(4, 1)
+	w.write(4, 0)
+	w.write(3, 2-1)
+	w.write(2, 0)
+	w.write(8, 0)
+	w.write(3, 3-1)
+	w.write(2, 2)
+	w.write(8, 0)
+	w.write(4*8, 0)
+	w.write(2, 1)
+	w.write(4, 7)
+	w.write((2+3+2)*7, 0)
+
+	w.write(6, 1-1)
+	w.write(16, 2)
+	w.write(24, 0)
+	w.write(24, 0)
+	w.write(24, 0)
+	w.write(6, 2-1)
+	w.write(8, 0)
+	w.write(3, 5)
+	w.write(1, 0)
+	w.write(3, 1)
+	w.write(1, 1)
+	w.write(5, 1)
+	w.write(4*8, 0) // 2 books for the first cascade, and 2 for the second
+
+	w.write(6, 1-1)
+	w.write(16, 0)
+	w.write(1, 1)
+	w.write(4, 2-1)
+	w.write(1, 1)
+	w.write(8, 1-1)
+	w.write(2*1, 0) // ilog(2-1) for each of magnitude and angle
+	w.write(2, 0)
+	w.write(2*4, 0)
+	w.write(2*3*8, 0)
+
+	w.write(6, 2-1)
+	w.write(1, 0)
+	w.write(16, 0)
+	w.write(16, 0)
+	w.write(8, 0)
+	w.write(1, 1)
+	w.write(16, 0)
+	w.write(16, 0)
+	w.write(8, 0)
+
+	w.write(1, 1)
+	return w.b
+}
+
+func TestIdentRoundTrip(t *testing.T) {
+	id := Ident{Channels: 2, SampleRate: 44100, BitrateNominal: 128000, BitrateMaximum: -1, Blocksize0: 256, Blocksize1: 2048}
+	b, err := id.MarshalBinary()
+	if err != nil {
+		t.Fatal("unexpected MarshalBinary error:", err)
+	}
+	if len(b) != 30 || b[28] != 0xb8 || b[29] != 1 {
+		t.Fatalf("bytes are wrong: %q", b)
+	}
+
+	var got Ident
+	err = got.UnmarshalBinary(b)
+	if err != nil {
+		t.Fatal("unexpected UnmarshalBinary error:", err)
+	}
+	if got != id {
+		t.Fatalf("expected %+v, got %+v", id, got)
+	}
+
+	b[28] = 0x8b
+	err = got.UnmarshalBinary(b)
+	if err != ErrIdent {
+		t.Fatal("expected ErrIdent for backward blocksizes, got", err)
+	}
+	b[28] = 0xb8
+	b[29] = 0
+	err = got.UnmarshalBinary(b)
+	if err != ErrFraming {
+		t.Fatal("expected ErrFraming, got", err)
+	}
+}
+
+func TestCommentRoundTrip(t *testing.T) {
+	c := Comment{comment.Header{Vendor: "test", Comments: []string{"TITLE=Song"}}}
+	b, err := c.MarshalBinary()
+	if err != nil {
+		t.Fatal("unexpected MarshalBinary error:", err)
+	}
+
+	var got Comment
+	err = got.UnmarshalBinary(b)
+	if err != nil {
+		t.Fatal("unexpected UnmarshalBinary error:", err)
+	}
+	if !reflect.DeepEqual(got, c) {
+		t.Fatalf("expected %+v, got %+v", c, got)
+	}
+
+	err = got.UnmarshalBinary(b[:len(b)-1])
+	if err != ErrShort {
+		t.Fatal("expected ErrShort, got", err)
+	}
+}
+
+func TestParseSetup(t *testing.T) {
+	id := Ident{Channels: 2}
+	b := testSetup()
+	s, err := ParseSetup(&id, b)
+	if err != nil {
+		t.Fatal("unexpected ParseSetup error:", err)
+	}
+	expect := []Mode{{}, {BlockFlag: true}}
+	if !reflect.DeepEqual(s.Modes, expect) {
+		t.Fatalf("expected modes %+v, got %+v", expect, s.Modes)
+	}
+
+	for i := len(setupMagic); i < len(b); i++ {
+		_, err = ParseSetup(&id, b[:i])
+		if err == nil {
+			t.Fatalf("expected an error for a setup header of %d bytes", i)
+		}
+	}
+}
+
+func TestReaderSamples(t *testing.T) {
+	id := Ident{Channels: 2, SampleRate: 44100, Blocksize0: 256, Blocksize1: 2048}
+	c := Comment{comment.Header{Vendor: "test"}}
+	ib, _ := id.MarshalBinary()
+	cb, _ := c.MarshalBinary()
+
+	var b bytes.Buffer
+	e := ogg.NewEncoder(1, &b)
+	err := e.EncodeBOS(0, [][]byte{ib})
+	if err != nil {
+		t.Fatal("unexpected EncodeBOS error:", err)
+	}
+	err = e.Encode(0, [][]byte{cb, testSetup()})
+	if err != nil {
+		t.Fatal("unexpected Encode error:", err)
+	}
+	short, long := []byte{0}, []byte{2}
+	err = e.EncodeEOS(2176, [][]byte{short, long, long, short})
+	if err != nil {
+		t.Fatal("unexpected EncodeEOS error:", err)
+	}
+
+	r, err := NewReader(&b)
+	if err != nil {
+		t.Fatal("unexpected NewReader error:", err)
+	}
+	if r.Serial != 1 || r.Ident != id || !reflect.DeepEqual(r.Comment, c) || len(r.Setup.Modes) != 2 {
+		t.Fatalf("headers are wrong: %d %+v %+v %+v", r.Serial, r.Ident, r.Comment, r.Setup)
+	}
+
+	counter := r.NewCounter()
+	var total int
+	for _, x := range []int{0, 64 + 512, 1024, 512 + 64} {
+		p, err := r.ReadPacket()
+		if err != nil {
+			t.Fatal("unexpected ReadPacket error:", err)
+		}
+		n, err := counter.Samples(p.Data)
+		if err != nil {
+			t.Fatal("unexpected Samples error:", err)
+		}
+		if n != x {
+			t.Fatalf("expected %d samples, got %d", x, n)
+		}
+		total += n
+	}
+	if total != 2176 {
+		t.Fatal("expected 2176 samples, got", total)
+	}
+
+	_, err = r.ReadPacket()
+	if err != io.EOF {
+		t.Fatal("expected EOF, got", err)
+	}
+	_, err = counter.Samples(ib)
+	if err != ErrNotAudio {
+		t.Fatal("expected ErrNotAudio, got", err)
+	}
+}